@@ -4,9 +4,16 @@ import (
 	"TestVK/internal/config"
 	"TestVK/internal/db"
 	"TestVK/internal/filmoteka"
+	"TestVK/internal/jwt"
 	"TestVK/internal/logger"
+	"TestVK/internal/migrations"
+	"TestVK/internal/rbac"
+	"context"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/lib/pq"
 )
@@ -29,15 +36,72 @@ func run() error {
 		return err
 	}
 
-	db, err := db.Connection(config.DB)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		return migrate(config, logger, os.Args[2:])
+	}
+
+	var store db.Store
+	switch config.DB.Driver {
+	case "memory":
+		logger.Warn("Using in-memory storage, data will be lost on restart")
+		store = db.NewMemory()
+	default:
+		conn, err := db.Connection(config.DB)
+		if err != nil {
+			logger.Error("Error creating db", "error", err.Error())
+			return err
+		}
+
+		defer conn.Close()
+
+		if config.DB.AutoMigrate {
+			migrator, err := migrations.NewMigrator(conn, logger)
+			if err != nil {
+				return err
+			}
+			if err := migrator.Up(context.Background()); err != nil {
+				logger.Error("Error applying migrations", "error", err.Error())
+				return err
+			}
+		}
+
+		store = db.NewPostgres(conn)
+	}
+
+	var verifier *jwt.Verifier
+	if mode := config.Auth.Mode; mode == filmoteka.AuthModeJWT || mode == filmoteka.AuthModeAny {
+		verifier, err = jwt.NewVerifier(config.Auth.JWT)
+		if err != nil {
+			logger.Error("Error loading jwt keys", "error", err.Error())
+			return err
+		}
+		go reloadOnHangup(verifier, logger)
+	}
+
+	policy, err := rbac.NewPolicy(config.RBAC)
 	if err != nil {
-		logger.Error("Error creating db", err.Error())
+		logger.Error("Error loading rbac policy", "error", err.Error())
 		return err
 	}
 
-	defer db.Close()
-
-	filmoteka := filmoteka.NewFilmoteka(db, config, logger)
+	filmoteka := filmoteka.NewFilmoteka(store, config, logger)
+	filmoteka.Verifier = verifier
+	filmoteka.Policy = policy
+	go filmoteka.RefreshRecommendations(context.Background())
 	filmoteka.Api()
 	return nil
 }
+
+// reloadOnHangup перечитывает ключи проверки JWT по SIGHUP, чтобы новые ключи
+// можно было добавить без перезапуска приложения.
+func reloadOnHangup(verifier *jwt.Verifier, logger *slog.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := verifier.Reload(); err != nil {
+			logger.Error("Error reloading jwt keys", "error", err.Error())
+			continue
+		}
+		logger.Info("JWT keys reloaded")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"TestVK/internal/config"
+	"TestVK/internal/db"
+	"TestVK/internal/migrations"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+var errMigrateUsage = errors.New("usage: app migrate [-dry-run] up | down [steps] | status")
+
+func migrate(config *config.AppConfig, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print SQL instead of executing it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	command := "up"
+	if flags.NArg() > 0 {
+		command = flags.Arg(0)
+	}
+
+	conn, err := db.Connection(config.DB)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := migrations.NewMigrator(conn, logger)
+	if err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+	migrator.Out = os.Stdout
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return errMigrateUsage
+	}
+}
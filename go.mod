@@ -1,18 +1,21 @@
 module TestVK
 
-go 1.21
+go 1.23
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/fatih/color v1.16.0
+	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.31.0
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/fatih/color v1.16.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
-	golang.org/x/sys v0.14.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -3,14 +3,17 @@ package config
 import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"time"
 )
 
 type DBConfig struct {
-	Password string `yaml:"password"`
-	Host     string `yaml:"host"`
-	SSLMode  string `yaml:"ssl_mode"`
-	User     string `yaml:"user"`
-	Port     string `yaml:"port"`
+	Driver      string `yaml:"driver"`
+	Password    string `yaml:"password"`
+	Host        string `yaml:"host"`
+	SSLMode     string `yaml:"ssl_mode"`
+	User        string `yaml:"user"`
+	Port        string `yaml:"port"`
+	AutoMigrate bool   `yaml:"auto_migrate"`
 }
 
 type LoggerConfig struct {
@@ -18,14 +21,94 @@ type LoggerConfig struct {
 	Level string `yaml:"level"`
 }
 
+type JWTKey struct {
+	ID            string `yaml:"kid"`
+	Algorithm     string `yaml:"alg"`
+	Secret        string `yaml:"secret"`
+	PublicKeyFile string `yaml:"public_key_file"`
+}
+
+type JWTConfig struct {
+	Issuer    string        `yaml:"issuer"`
+	Audience  string        `yaml:"audience"`
+	RoleClaim string        `yaml:"role_claim"`
+	UserClaim string        `yaml:"user_claim"`
+	Leeway    time.Duration `yaml:"leeway"`
+	JWKSFile  string        `yaml:"jwks_file"`
+	Keys      []JWTKey      `yaml:"keys"`
+}
+
 type AuthToken struct {
-	Admin string `yaml:"admin"`
+	Mode     string        `yaml:"mode"`
+	Admin    string        `yaml:"admin"`
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	JWT      JWTConfig     `yaml:"jwt"`
+}
+
+type RoleConfig struct {
+	Inherits    []string `yaml:"inherits"`
+	Permissions []string `yaml:"permissions"`
+}
+
+type RBACConfig struct {
+	Roles map[string]RoleConfig `yaml:"roles"`
+}
+
+type PaginationConfig struct {
+	DefaultLimit int `yaml:"default_limit"`
+	MaxLimit     int `yaml:"max_limit"`
+}
+
+type HTTPConfig struct {
+	LegacyRoutes bool             `yaml:"legacy_routes"`
+	Pagination   PaginationConfig `yaml:"pagination"`
+}
+
+type SearchConfig struct {
+	SimilarityThreshold float64 `yaml:"similarity_threshold"`
+	Suggestions         int     `yaml:"suggestions"`
+}
+
+type RatingsConfig struct {
+	BayesianMinVotes int     `yaml:"bayesian_min_votes"`
+	BayesianPrior    float64 `yaml:"bayesian_prior"`
+}
+
+type RecommendationWeights struct {
+	Actors  float64 `yaml:"actors"`
+	Genres  float64 `yaml:"genres"`
+	Era     float64 `yaml:"era"`
+	Ratings float64 `yaml:"ratings"`
+}
+
+type RecommendationsConfig struct {
+	RefreshInterval time.Duration         `yaml:"refresh_interval"`
+	Neighbours      int                   `yaml:"neighbours"`
+	EraYears        int                   `yaml:"era_years"`
+	MinCoRaters     int                   `yaml:"min_co_raters"`
+	Weights         RecommendationWeights `yaml:"weights"`
+}
+
+type StatsConfig struct {
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+type I18nConfig struct {
+	DefaultLanguage string `yaml:"default_language"`
 }
 
 type AppConfig struct {
-	DB     DBConfig     `yaml:"db"`
-	Logger LoggerConfig `yaml:"logger"`
-	Auth   AuthToken    `yaml:"auth_token"`
+	DB      DBConfig      `yaml:"db"`
+	Logger  LoggerConfig  `yaml:"logger"`
+	Auth    AuthToken     `yaml:"auth_token"`
+	RBAC    RBACConfig    `yaml:"rbac"`
+	HTTP    HTTPConfig    `yaml:"http"`
+	Search  SearchConfig  `yaml:"search"`
+	Ratings RatingsConfig `yaml:"ratings"`
+	Stats   StatsConfig   `yaml:"stats"`
+	I18n    I18nConfig    `yaml:"i18n"`
+
+	Recommendations RecommendationsConfig `yaml:"recommendations"`
 }
 
 func NewConfig(path string) (*AppConfig, error) {
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Типы участия актёра в фильме.
+const (
+	CreditLead       = "lead"
+	CreditSupporting = "supporting"
+	CreditCameo      = "cameo"
+	CreditVoice      = "voice"
+)
+
+var ErrUnknownCreditType = errors.New("unknown credit type")
+
+// CastMember - актёр в составе фильма. Billing - место в титрах начиная с 1,
+// 0 означает, что место не задано.
+type CastMember struct {
+	ActorID    int    `json:"actor_id"`
+	Name       string `json:"name"`
+	Character  string `json:"character,omitempty"`
+	Billing    int    `json:"billing,omitempty"`
+	CreditType string `json:"credit_type,omitempty"`
+}
+
+// MovieCredit - фильм из фильмографии актёра вместе с его ролью в нём.
+type MovieCredit struct {
+	Movie
+	Character  string `json:"character,omitempty"`
+	Billing    int    `json:"billing,omitempty"`
+	CreditType string `json:"credit_type,omitempty"`
+}
+
+const maxCharacterLength = 255
+
+func validCreditType(creditType string) bool {
+	switch creditType {
+	case "", CreditLead, CreditSupporting, CreditCameo, CreditVoice:
+		return true
+	}
+	return false
+}
+
+func movieCreditKey(credit MovieCredit) (string, int) {
+	return "", credit.ID
+}
+
+// castQuery возвращает состав фильма в порядке титров; актёры без места идут в конце.
+const castQuery = `
+	SELECT a.id, a.name, coalesce(ma.character_name, ''), coalesce(ma.billing, 0), coalesce(ma.credit_type, '')
+	FROM movie_actors ma
+	INNER JOIN actors a ON a.id = ma.actor_id
+	WHERE ma.movie_id = $1
+	ORDER BY ma.billing NULLS LAST, a.id
+`
+
+func queryCast(q queryer, movieID int) ([]CastMember, error) {
+	rows, err := q.Query(castQuery, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cast := []CastMember{}
+	for rows.Next() {
+		var member CastMember
+		if err := rows.Scan(&member.ActorID, &member.Name, &member.Character, &member.Billing, &member.CreditType); err != nil {
+			return nil, err
+		}
+		cast = append(cast, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cast, nil
+}
+
+// lockMovie блокирует строку фильма до конца транзакции, чтобы параллельные
+// изменения состава одного фильма выполнялись по очереди.
+func lockMovie(tx *sql.Tx, movieID int) error {
+	var id int
+	err := tx.QueryRow(`SELECT id FROM movies WHERE id = $1 FOR UPDATE`, movieID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (p *Postgres) GetMovieCast(movieID int) ([]CastMember, error) {
+	if _, err := p.GetMovie(movieID); err != nil {
+		return nil, err
+	}
+	return queryCast(p.db, movieID)
+}
+
+// ReplaceMovieCast приводит состав фильма к cast за одну транзакцию: лишние
+// связи удаляются, новые добавляются, у оставшихся обновляются роль и место.
+func (p *Postgres) ReplaceMovieCast(movieID int, cast []CastMember) ([]CastMember, error) {
+	if err := checkCast(cast); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return nil, err
+	}
+
+	current, err := queryCast(tx, movieID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[int]CastMember, len(current))
+	for _, member := range current {
+		existing[member.ActorID] = member
+	}
+
+	for _, member := range cast {
+		old, ok := existing[member.ActorID]
+		delete(existing, member.ActorID)
+
+		switch {
+		case !ok:
+			_, err = tx.Exec(`
+				INSERT INTO movie_actors (movie_id, actor_id, character_name, billing, credit_type)
+				VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, 0), NULLIF($5, ''))
+			`, movieID, member.ActorID, member.Character, member.Billing, member.CreditType)
+		case old.Character != member.Character || old.Billing != member.Billing || old.CreditType != member.CreditType:
+			_, err = tx.Exec(`
+				UPDATE movie_actors
+				SET character_name = NULLIF($3, ''), billing = NULLIF($4, 0), credit_type = NULLIF($5, '')
+				WHERE movie_id = $1 AND actor_id = $2
+			`, movieID, member.ActorID, member.Character, member.Billing, member.CreditType)
+		}
+		if err != nil {
+			return nil, convertError(err)
+		}
+	}
+
+	for actorID := range existing {
+		if _, err := tx.Exec(`DELETE FROM movie_actors WHERE movie_id = $1 AND actor_id = $2`, movieID, actorID); err != nil {
+			return nil, convertError(err)
+		}
+	}
+
+	result, err := queryCast(tx, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, tx.Commit()
+}
+
+// RemoveMovieActor убирает актёра из состава фильма и возвращает оставшийся состав.
+func (p *Postgres) RemoveMovieActor(movieID, actorID int) ([]CastMember, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM movie_actors WHERE movie_id = $1 AND actor_id = $2`, movieID, actorID)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	if err := checkAffected(result); err != nil {
+		return nil, err
+	}
+
+	cast, err := queryCast(tx, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cast, tx.Commit()
+}
+
+// checkCast проверяет то, что иначе нарушило бы ограничения таблицы movie_actors.
+func checkCast(cast []CastMember) error {
+	seen := make(map[int]struct{}, len(cast))
+	for _, member := range cast {
+		if _, ok := seen[member.ActorID]; ok {
+			return fmt.Errorf("%w: actor_id=%d", ErrDuplicateKey, member.ActorID)
+		}
+		seen[member.ActorID] = struct{}{}
+
+		if len([]rune(member.Character)) > maxCharacterLength {
+			return fmt.Errorf("%w: movie_actors.character_name", ErrValueTooLong)
+		}
+		if !validCreditType(member.CreditType) {
+			return fmt.Errorf("%w: %q", ErrUnknownCreditType, member.CreditType)
+		}
+	}
+	return nil
+}
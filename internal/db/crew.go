@@ -0,0 +1,235 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Должности в съёмочной группе в порядке вывода.
+const (
+	JobDirector        = "director"
+	JobWriter          = "writer"
+	JobProducer        = "producer"
+	JobComposer        = "composer"
+	JobCinematographer = "cinematographer"
+)
+
+var jobs = []string{JobDirector, JobWriter, JobProducer, JobComposer, JobCinematographer}
+
+var ErrUnknownJob = errors.New("unknown crew job")
+
+// CrewMember - человек из таблицы actors на должности в съёмочной группе фильма.
+type CrewMember struct {
+	PersonID int    `json:"person_id"`
+	Name     string `json:"name"`
+	Job      string `json:"job"`
+}
+
+// CrewCredit - фильм, в съёмочной группе которого работал человек.
+type CrewCredit struct {
+	Movie
+	Job string `json:"job"`
+}
+
+// jobOrder задаёт в SQL тот же порядок должностей, что и jobs.
+const jobOrder = `array_position(ARRAY['director', 'writer', 'producer', 'composer', 'cinematographer']::varchar[], mc.job)`
+
+const crewQuery = `
+	SELECT a.id, a.name, mc.job
+	FROM movie_crew mc
+	INNER JOIN actors a ON a.id = mc.person_id
+	WHERE mc.movie_id = $1
+	ORDER BY ` + jobOrder + `, a.name, a.id
+`
+
+func queryCrew(q queryer, movieID int) ([]CrewMember, error) {
+	rows, err := q.Query(crewQuery, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	crew := []CrewMember{}
+	for rows.Next() {
+		var member CrewMember
+		if err := rows.Scan(&member.PersonID, &member.Name, &member.Job); err != nil {
+			return nil, err
+		}
+		crew = append(crew, member)
+	}
+
+	return crew, rows.Err()
+}
+
+func (p *Postgres) GetMovieCrew(movieID int) ([]CrewMember, error) {
+	if _, err := p.GetMovie(movieID); err != nil {
+		return nil, err
+	}
+	return queryCrew(p.db, movieID)
+}
+
+// ReplaceMovieCrew приводит съёмочную группу фильма к crew за одну транзакцию.
+func (p *Postgres) ReplaceMovieCrew(movieID int, crew []CrewMember) ([]CrewMember, error) {
+	if err := checkCrew(crew); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM movie_crew WHERE movie_id = $1`, movieID); err != nil {
+		return nil, convertError(err)
+	}
+	for _, member := range crew {
+		if _, err := tx.Exec(`INSERT INTO movie_crew (movie_id, person_id, job) VALUES ($1, $2, $3)`,
+			movieID, member.PersonID, member.Job); err != nil {
+			return nil, convertError(err)
+		}
+	}
+
+	result, err := queryCrew(tx, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, tx.Commit()
+}
+
+// RemoveMovieCrewMember убирает человека из съёмочной группы фильма: с одной
+// должности или, если job пуст, со всех.
+func (p *Postgres) RemoveMovieCrewMember(movieID, personID int, job string) ([]CrewMember, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM movie_crew
+		WHERE movie_id = $1 AND person_id = $2 AND ($3 = '' OR job = $3)
+	`, movieID, personID, job)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	if err := checkAffected(result); err != nil {
+		return nil, err
+	}
+
+	crew, err := queryCrew(tx, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return crew, tx.Commit()
+}
+
+// GetPersonCrewCredits возвращает фильмы, в съёмочной группе которых работал
+// человек, новые первыми.
+func (p *Postgres) GetPersonCrewCredits(personID int) ([]CrewCredit, error) {
+	rows, err := p.db.Query(`
+		SELECT m.id, m.title, m.description, m.release_date, m.rating, mc.job
+		FROM movies m
+		INNER JOIN movie_crew mc ON mc.movie_id = m.id
+		WHERE mc.person_id = $1
+		ORDER BY m.release_date DESC, m.id, `+jobOrder, personID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []CrewCredit{}
+	for rows.Next() {
+		var credit CrewCredit
+		if err := rows.Scan(append(credit.fields(), &credit.Job)...); err != nil {
+			return nil, err
+		}
+		credits = append(credits, credit)
+	}
+
+	return credits, rows.Err()
+}
+
+// SearchMoviesByCrew ищет фильмы, в съёмочной группе которых есть человек с
+// именем name на должности job; пустой job означает любую должность.
+func (p *Postgres) SearchMoviesByCrew(name, job string, page Page) ([]Movie, PageInfo, error) {
+	if err := checkJob(job); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := `
+		SELECT DISTINCT m.id, m.title, m.description, m.release_date, m.rating
+		FROM movies m
+		INNER JOIN movie_crew mc ON m.id = mc.movie_id
+		INNER JOIN actors a ON mc.person_id = a.id
+		WHERE a.name = $1 AND ($2 = '' OR mc.job = $2)
+	`
+
+	return queryMovies(p.db, query, []interface{}{name, job}, sortByID, page)
+}
+
+// SearchMoviesByCrewFuzzy - SearchMoviesByCrew по сходству имён, как в SearchMoviesFuzzy.
+func (p *Postgres) SearchMoviesByCrewFuzzy(name, job string, threshold float64, page Page) ([]Movie, PageInfo, error) {
+	if err := checkJob(job); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := `
+		SELECT DISTINCT m.id, m.title, m.description, m.release_date, m.rating
+		FROM movies m
+		INNER JOIN movie_crew mc ON m.id = mc.movie_id
+		INNER JOIN actors a ON mc.person_id = a.id
+		WHERE $1 <% a.name AND ($2 = '' OR mc.job = $2)
+	`
+
+	var movies []Movie
+	var info PageInfo
+	err := p.withSimilarityThreshold(threshold, func(q queryer) error {
+		var err error
+		movies, info, err = queryMovies(q, query, []interface{}{name, job}, sortByID, page)
+		return err
+	})
+
+	return movies, info, err
+}
+
+func checkJob(job string) error {
+	if job != "" && !slices.Contains(jobs, job) {
+		return fmt.Errorf("%w: %q", ErrUnknownJob, job)
+	}
+	return nil
+}
+
+// checkCrew проверяет то, что иначе нарушило бы ограничения таблицы movie_crew.
+func checkCrew(crew []CrewMember) error {
+	type key struct {
+		personID int
+		job      string
+	}
+	seen := make(map[key]struct{}, len(crew))
+	for _, member := range crew {
+		if member.Job == "" {
+			return fmt.Errorf("%w: job is required", ErrUnknownJob)
+		}
+		if err := checkJob(member.Job); err != nil {
+			return err
+		}
+		k := key{member.PersonID, member.Job}
+		if _, ok := seen[k]; ok {
+			return fmt.Errorf("%w: person_id=%d job=%s", ErrDuplicateKey, member.PersonID, member.Job)
+		}
+		seen[k] = struct{}{}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"TestVK/internal/config"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -14,16 +15,53 @@ type Movie struct {
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	ReleaseDate time.Time `json:"release_date"`
-	Rating      float64   `json:"rating"`
+	Rating      *float64  `json:"rating"`
 }
 
 type Actor struct {
-	Id        int       `json:"id"`
-	Name      string    `json:"name"`
-	Gender    string    `json:"gender"`
-	Birthdate time.Time `json:"birthdate"`
+	Id        int        `json:"id"`
+	Name      string     `json:"name"`
+	Gender    string     `json:"gender"`
+	Birthdate *time.Time `json:"birthdate"`
 }
 
+// Ограничения полей актёров и фильмов. Длины совпадают с размерами столбцов
+// в миграциях.
+const (
+	MaxActorNameLength   = 255
+	MaxActorGenderLength = 10
+	MaxMovieTitleLength  = 150
+)
+
+// Optional - новое значение поля в частичном обновлении. Set = false - поле
+// не меняется, Null = true - поле сбрасывается в NULL.
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// ActorPatch - частичное обновление актёра.
+type ActorPatch struct {
+	Name      Optional[string]
+	Gender    Optional[string]
+	Birthdate Optional[time.Time]
+}
+
+// MoviePatch - частичное обновление фильма.
+type MoviePatch struct {
+	Title       Optional[string]
+	Description Optional[string]
+	ReleaseDate Optional[time.Time]
+}
+
+// Допустимые значения Actor.Gender; пустая строка означает, что пол не указан.
+const (
+	GenderMale   = "male"
+	GenderFemale = "female"
+	GenderOther  = "other"
+)
+
 func Connection(config config.DBConfig) (*sql.DB, error) {
 	connectionString := fmt.Sprintf("host=%s port=%s user=%s password=%s sslmode=%s", config.Host, config.Port, config.User, config.Password, config.SSLMode)
 
@@ -40,140 +78,141 @@ func Connection(config config.DBConfig) (*sql.DB, error) {
 	return db, nil
 }
 
-func AddActor(db *sql.DB, actor Actor) error {
+type Postgres struct {
+	db *sql.DB
+}
 
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// AddActor сохраняет актёра и возвращает его вместе с присвоенным id.
+func (p *Postgres) AddActor(actor Actor) (Actor, error) {
 	query := `
         INSERT INTO actors (name, gender, birthdate)
         VALUES ($1, $2, $3)
-    `
-
-	_, err := db.Exec(query, actor.Name, actor.Gender, actor.Birthdate)
-	if err != nil {
-		return err
-	}
+        RETURNING ` + actorColumns
 
-	return nil
+	return scanActor(p.db.QueryRow(query, actor.Name, actor.Gender, actor.Birthdate))
 }
 
-func UpdateActor(db *sql.DB, actor Actor) error {
-	query := `UPDATE actors SET `
-	args := make([]interface{}, 0)
-	argCounter := 1
+// UpdateActor меняет переданные в patch поля актёра и возвращает его новое
+// состояние.
+func (p *Postgres) UpdateActor(actorID int, patch ActorPatch) (Actor, error) {
+	var set assignments
+	assign(&set, "name", patch.Name)
+	assign(&set, "gender", patch.Gender)
+	assign(&set, "birthdate", patch.Birthdate)
 
-	if actor.Name != "" {
-		query += "name = $" + strconv.Itoa(argCounter) + ", "
-		args = append(args, actor.Name)
-		argCounter++
-	}
-	if actor.Gender != "" {
-		query += "gender = $" + strconv.Itoa(argCounter) + ", "
-		args = append(args, actor.Gender)
-		argCounter++
-	}
-	if !actor.Birthdate.IsZero() {
-		query += "birthdate = $" + strconv.Itoa(argCounter) + ", "
-		args = append(args, actor.Birthdate)
-		argCounter++
+	if len(set.columns) == 0 {
+		return p.GetActor(actorID)
 	}
 
-	query = strings.TrimSuffix(query, ", ")
+	query := `UPDATE actors SET ` + set.String() + ` WHERE id = $1 RETURNING ` + actorColumns
 
-	query += " WHERE id = $1"
+	return scanActor(p.db.QueryRow(query, set.with(actorID)...))
+}
 
-	args = append([]interface{}{actor.Id}, args...)
+// ReplaceActor перезаписывает все поля актёра, в том числе нулевыми значениями.
+func (p *Postgres) ReplaceActor(actor Actor) (Actor, error) {
+	query := `UPDATE actors SET name = $2, gender = $3, birthdate = $4 WHERE id = $1 RETURNING ` + actorColumns
 
-	_, err := db.Exec(query, args...)
-	if err != nil {
-		return err
-	}
+	return scanActor(p.db.QueryRow(query, actor.Id, actor.Name, actor.Gender, actor.Birthdate))
+}
 
-	return nil
+func (p *Postgres) GetActor(actorID int) (Actor, error) {
+	query := `
+        SELECT ` + actorColumns + `
+        FROM actors
+        WHERE id = $1
+    `
+
+	return scanActor(p.db.QueryRow(query, actorID))
 }
 
-func DeleteActor(db *sql.DB, actorID int) error {
+func (p *Postgres) DeleteActor(actorID int) error {
 	query := `DELETE FROM actors WHERE id = $1`
 
-	_, err := db.Exec(query, actorID)
+	result, err := p.db.Exec(query, actorID)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
-	return nil
+	return checkAffected(result)
 }
 
-func AddMovie(db *sql.DB, movie Movie) error {
+// AddMovie сохраняет фильм и возвращает его вместе с присвоенным id. Rating -
+// рейтинг фильма без оценок, см. RatingPrior.Unrated.
+func (p *Postgres) AddMovie(movie Movie) (Movie, error) {
 	query := `
         INSERT INTO movies (title, description, release_date, rating)
         VALUES ($1, $2, $3, $4)
-    `
+        RETURNING ` + movieColumns
 
-	_, err := db.Exec(query, movie.Title, movie.Description, movie.ReleaseDate, movie.Rating)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return scanMovie(p.db.QueryRow(query, movie.Title, movie.Description, nullTime(movie.ReleaseDate), movie.Rating))
 }
 
-func UpdateMovie(db *sql.DB, movie Movie) error {
-	query := "UPDATE movies SET "
-	var args []interface{}
+// UpdateMovie меняет переданные в patch поля фильма и возвращает его новое
+// состояние.
+func (p *Postgres) UpdateMovie(movieID int, patch MoviePatch) (Movie, error) {
+	var set assignments
+	assign(&set, "title", patch.Title)
+	assign(&set, "description", patch.Description)
+	assign(&set, "release_date", patch.ReleaseDate)
 
-	if movie.Title != "" {
-		query += "title = $2, "
-		args = append(args, movie.Title)
-	}
-	if movie.Description != "" {
-		query += "description = $3, "
-		args = append(args, movie.Description)
-	}
-	if !movie.ReleaseDate.IsZero() {
-		query += "release_date = $4, "
-		args = append(args, movie.ReleaseDate)
-	}
-	if movie.Rating != 0 {
-		query += "rating = $5, "
-		args = append(args, movie.Rating)
+	if len(set.columns) == 0 {
+		return p.GetMovie(movieID)
 	}
 
-	query = strings.TrimSuffix(query, ", ")
+	query := `UPDATE movies SET ` + set.String() + ` WHERE id = $1 RETURNING ` + movieColumns
 
-	query += " WHERE id = $1"
+	return scanMovie(p.db.QueryRow(query, set.with(movieID)...))
+}
 
-	args = append([]interface{}{movie.ID}, args...)
+// ReplaceMovie перезаписывает все поля фильма, в том числе нулевыми
+// значениями. Рейтинг вычисляется по отзывам и не меняется.
+func (p *Postgres) ReplaceMovie(movie Movie) (Movie, error) {
+	query := `
+        UPDATE movies SET title = $2, description = $3, release_date = $4
+        WHERE id = $1
+        RETURNING ` + movieColumns
 
-	// Выполнение SQL запроса
-	_, err := db.Exec(query, args...)
-	if err != nil {
-		return err
-	}
+	return scanMovie(p.db.QueryRow(query, movie.ID, movie.Title, movie.Description, nullTime(movie.ReleaseDate)))
+}
 
-	return nil
+func (p *Postgres) GetMovie(movieID int) (Movie, error) {
+	query := `
+        SELECT ` + movieColumns + `
+        FROM movies
+        WHERE id = $1
+    `
+
+	return scanMovie(p.db.QueryRow(query, movieID))
 }
 
-func DeleteMovie(db *sql.DB, movieID int) error {
+func (p *Postgres) DeleteMovie(movieID int) error {
 	query := `DELETE FROM movies WHERE id = $1`
 
-	_, err := db.Exec(query, movieID)
+	result, err := p.db.Exec(query, movieID)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
-	return nil
+	return checkAffected(result)
 }
 
-func AddMovieActor(db *sql.DB, movieID, actorID int) error {
+func (p *Postgres) AddMovieActor(movieID, actorID int) error {
 	query := `INSERT INTO movie_actors (movie_id, actor_id) VALUES ($1, $2)`
 
-	_, err := db.Exec(query, movieID, actorID)
+	_, err := p.db.Exec(query, movieID, actorID)
 	if err != nil {
-		return err
+		return convertError(err)
 	}
 
 	return nil
 }
 
-func SearchMoviesByActorName(db *sql.DB, actorName string) ([]Movie, error) {
+func (p *Postgres) SearchMoviesByActorName(actorName string, page Page) ([]Movie, PageInfo, error) {
 	query := `
         SELECT m.id, m.title, m.description, m.release_date, m.rating
         FROM movies m
@@ -182,50 +221,25 @@ func SearchMoviesByActorName(db *sql.DB, actorName string) ([]Movie, error) {
         WHERE a.name = $1
     `
 
-	rows, err := db.Query(query, actorName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var movies []Movie
-	for rows.Next() {
-		var movie Movie
-		err := rows.Scan(&movie.ID, &movie.Title, &movie.Description, &movie.ReleaseDate, &movie.Rating)
-		if err != nil {
-			return nil, err
-		}
-		movies = append(movies, movie)
-	}
-
-	return movies, nil
+	return queryMovies(p.db, query, []interface{}{actorName}, sortByID, page)
 }
 
-func GetMoviesWithSorting(db *sql.DB, orderBy, sortOrder string) ([]Movie, error) {
-	query := fmt.Sprintf("SELECT id, title, description, release_date, rating FROM movies ORDER BY %s %s", orderBy, sortOrder)
-
-	rows, err := db.Query(query)
+func (p *Postgres) GetMoviesWithSorting(filter MovieFilter, orderBy, sortOrder string, page Page) ([]Movie, PageInfo, error) {
+	sort, err := movieSort(orderBy, sortOrder)
 	if err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
-	defer rows.Close()
 
-	var movies []Movie
-	for rows.Next() {
-		var movie Movie
-		if err := rows.Scan(&movie.ID, &movie.Title, &movie.Description, &movie.ReleaseDate, &movie.Rating); err != nil {
-			return nil, err
-		}
-		movies = append(movies, movie)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+	where, args := filter.where(nil)
+	query := `
+        SELECT id, title, description, release_date, rating
+        FROM movies
+    ` + where
 
-	return movies, nil
+	return queryMovies(p.db, query, args, sort, page)
 }
 
-func SearchMoviesByTitleOrActorName(db *sql.DB, titleFragment, actorNameFragment string) ([]Movie, error) {
+func (p *Postgres) SearchMoviesByTitleOrActorName(titleFragment, actorNameFragment string, page Page) ([]Movie, PageInfo, error) {
 	query := `
 		SELECT DISTINCT m.id, m.title, m.description, m.release_date, m.rating
 		FROM movies m
@@ -234,80 +248,276 @@ func SearchMoviesByTitleOrActorName(db *sql.DB, titleFragment, actorNameFragment
 		WHERE m.title LIKE $1 OR a.name LIKE $2
     `
 
-	rows, err := db.Query(query, "%"+titleFragment+"%", "%"+actorNameFragment+"%")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	return queryMovies(p.db, query, []interface{}{"%" + escapeLike(titleFragment) + "%", "%" + escapeLike(actorNameFragment) + "%"}, sortByID, page)
+}
 
-	var movies []Movie
-	for rows.Next() {
-		var movie Movie
-		if err := rows.Scan(&movie.ID, &movie.Title, &movie.Description, &movie.ReleaseDate, &movie.Rating); err != nil {
-			return nil, err
-		}
-		movies = append(movies, movie)
+func (p *Postgres) GetActors(page Page) ([]Actor, PageInfo, error) {
+	if err := page.check(sortByID); err != nil {
+		return nil, PageInfo{}, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return movies, nil
-}
 
-func GetActors(db *sql.DB) ([]Actor, error) {
 	query := `
         SELECT id, name, gender, birthdate
         FROM actors
     `
 
-	rows, err := db.Query(query)
+	var total int
+	if err := p.db.QueryRow(countQuery(query)).Scan(&total); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	pageQuery, args := paginateQuery(query, nil, sortByID, page)
+	rows, err := p.db.Query(pageQuery, args...)
 	if err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
 	defer rows.Close()
 
 	var actors []Actor
 	for rows.Next() {
 		var actor Actor
-		if err := rows.Scan(&actor.Id, &actor.Name, &actor.Gender, &actor.Birthdate); err != nil {
-			return nil, err
+		if err := rows.Scan(actor.fields()...); err != nil {
+			return nil, PageInfo{}, err
 		}
 		actors = append(actors, actor)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
 	}
 
-	return actors, nil
+	reverse(actors, page)
+	actors, info := pageInfo(actors, page, sortByID, total, actorKey)
+
+	return actors, info, nil
 }
 
-func GetMoviesByActorName(db *sql.DB, actorName string) ([]Movie, error) {
+func (p *Postgres) GetMoviesByActorName(actorName string, page Page) ([]MovieCredit, PageInfo, error) {
 	query := `
-        SELECT m.id, m.title, m.description, m.release_date, m.rating
+        SELECT m.id, m.title, m.description, m.release_date, m.rating,
+               coalesce(ma.character_name, '') AS character_name,
+               coalesce(ma.billing, 0) AS billing,
+               coalesce(ma.credit_type, '') AS credit_type
         FROM movies m
         INNER JOIN movie_actors ma ON m.id = ma.movie_id
         INNER JOIN actors a ON ma.actor_id = a.id
         WHERE a.name = $1
     `
 
-	rows, err := db.Query(query, actorName)
+	return queryCredits(p.db, query, []interface{}{actorName}, page)
+}
+
+func (p *Postgres) GetMoviesByActorID(actorID int, page Page) ([]MovieCredit, PageInfo, error) {
+	query := `
+        SELECT m.id, m.title, m.description, m.release_date, m.rating,
+               coalesce(ma.character_name, '') AS character_name,
+               coalesce(ma.billing, 0) AS billing,
+               coalesce(ma.credit_type, '') AS credit_type
+        FROM movies m
+        INNER JOIN movie_actors ma ON m.id = ma.movie_id
+        WHERE ma.actor_id = $1
+    `
+
+	return queryCredits(p.db, query, []interface{}{actorID}, page)
+}
+
+// GetActorFilmography возвращает все фильмы актёра с его ролями, новые первыми.
+func (p *Postgres) GetActorFilmography(actorID int) ([]MovieCredit, error) {
+	rows, err := p.db.Query(`
+        SELECT m.id, m.title, m.description, m.release_date, m.rating,
+               coalesce(ma.character_name, ''), coalesce(ma.billing, 0), coalesce(ma.credit_type, '')
+        FROM movies m
+        INNER JOIN movie_actors ma ON m.id = ma.movie_id
+        WHERE ma.actor_id = $1
+        ORDER BY m.release_date DESC, m.id
+    `, actorID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var movies []Movie
+	credits := []MovieCredit{}
 	for rows.Next() {
-		var movie Movie
-		if err := rows.Scan(&movie.ID, &movie.Title, &movie.Description, &movie.ReleaseDate, &movie.Rating); err != nil {
+		var credit MovieCredit
+		if err := rows.Scan(append(credit.fields(), &credit.Character, &credit.Billing, &credit.CreditType)...); err != nil {
 			return nil, err
 		}
-		movies = append(movies, movie)
+		credits = append(credits, credit)
+	}
+
+	return credits, rows.Err()
+}
+
+func queryCredits(q queryer, query string, args []interface{}, page Page) ([]MovieCredit, PageInfo, error) {
+	return queryPage(q, query, args, sortByID, page, movieCreditKey, func(rows *sql.Rows, credit *MovieCredit) error {
+		return rows.Scan(append(credit.fields(), &credit.Character, &credit.Billing, &credit.CreditType)...)
+	})
+}
+
+// queryer - общее подмножество *sql.DB и *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// queryMovies выполняет запрос фильмов постранично.
+func queryMovies(q queryer, query string, args []interface{}, sort Sort, page Page) ([]Movie, PageInfo, error) {
+	return queryPage(q, query, args, sort, page, movieKey(sort.Column), func(rows *sql.Rows, movie *Movie) error {
+		return rows.Scan(movie.fields()...)
+	})
+}
+
+// queryPage выполняет запрос постранично: сначала считает все строки базового
+// запроса, затем читает страницу по смещению или курсору.
+func queryPage[T any](q queryer, query string, args []interface{}, sort Sort, page Page,
+	key func(T) (string, int), scan func(*sql.Rows, *T) error) ([]T, PageInfo, error) {
+	if err := page.check(sort); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var total int
+	if err := q.QueryRow(countQuery(query), args...).Scan(&total); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	pageQuery, pageArgs := paginateQuery(query, args, sort, page)
+	rows, err := q.Query(pageQuery, pageArgs...)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		var item T
+		if err := scan(rows, &item); err != nil {
+			return nil, PageInfo{}, err
+		}
+		items = append(items, item)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, PageInfo{}, err
+	}
+
+	reverse(items, page)
+	items, info := pageInfo(items, page, sort, total, key)
+
+	return items, info, nil
+}
+
+// Столбцы, которые GetActor/GetMovie читают, а вставки и обновления
+// возвращают через RETURNING.
+const (
+	actorColumns = `id, name, gender, birthdate`
+	movieColumns = `id, title, description, release_date, rating`
+)
+
+// fields возвращает приёмники Scan для actorColumns. NULL в поле пола
+// читается как пустая строка, в дате рождения - как nil.
+func (a *Actor) fields() []interface{} {
+	return []interface{}{&a.Id, &a.Name, nullable(&a.Gender), &a.Birthdate}
+}
+
+// fields возвращает приёмники Scan для movieColumns. NULL в необязательных
+// столбцах читается как нулевое значение, рейтинг без оценок - как nil.
+func (m *Movie) fields() []interface{} {
+	return []interface{}{&m.ID, &m.Title, nullable(&m.Description), nullable(&m.ReleaseDate), &m.Rating}
+}
+
+// nullableScanner читает NULL в нулевое значение *dest.
+type nullableScanner[T any] struct {
+	dest *T
+}
+
+func nullable[T any](dest *T) nullableScanner[T] {
+	return nullableScanner[T]{dest: dest}
+}
+
+func (n nullableScanner[T]) Scan(src interface{}) error {
+	var value sql.Null[T]
+	if err := value.Scan(src); err != nil {
+		return err
+	}
+	*n.dest = value.V
+	return nil
+}
+
+// nullTime возвращает NULL для нулевой даты выхода, которую Scan читает из
+// NULL обратно как нулевую.
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// scanActor читает строку с actorColumns; отсутствие строки - ErrNotFound.
+func scanActor(row *sql.Row) (Actor, error) {
+	var actor Actor
+	err := row.Scan(actor.fields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Actor{}, ErrNotFound
+	}
+	if err != nil {
+		return Actor{}, convertError(err)
+	}
+
+	return actor, nil
+}
+
+// scanMovie читает строку с movieColumns; отсутствие строки - ErrNotFound.
+func scanMovie(row *sql.Row) (Movie, error) {
+	var movie Movie
+	err := row.Scan(movie.fields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Movie{}, ErrNotFound
+	}
+	if err != nil {
+		return Movie{}, convertError(err)
+	}
+
+	return movie, nil
+}
+
+// assignments собирает SET-часть UPDATE с параметрами; $1 оставлен под id
+// изменяемой строки.
+type assignments struct {
+	columns []string
+	args    []interface{}
+}
+
+func (a *assignments) add(column string, value interface{}) {
+	a.args = append(a.args, value)
+	a.columns = append(a.columns, column+" = $"+strconv.Itoa(len(a.args)+1))
+}
+
+// assign добавляет изменение столбца, если поле передано; Null записывает
+// NULL.
+func assign[T any](a *assignments, column string, field Optional[T]) {
+	switch {
+	case !field.Set:
+	case field.Null:
+		a.columns = append(a.columns, column+" = NULL")
+	default:
+		a.add(column, field.Value)
 	}
+}
+
+func (a *assignments) String() string {
+	return strings.Join(a.columns, ", ")
+}
+
+// with возвращает параметры запроса: id и значения столбцов.
+func (a *assignments) with(id int) []interface{} {
+	return append([]interface{}{id}, a.args...)
+}
 
-	return movies, nil
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
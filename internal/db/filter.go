@@ -0,0 +1,126 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieFilter ограничивает выборку фильмов. Пустые поля не участвуют в отборе,
+// границы диапазонов включаются в выборку.
+type MovieFilter struct {
+	ReleasedFrom time.Time
+	ReleasedTo   time.Time
+	MinRating    *float64
+	MaxRating    *float64
+	// ActorIDs оставляет фильмы, в которых снимался хотя бы один из актёров.
+	ActorIDs []int
+	// GenreIDs оставляет фильмы хотя бы одного из жанров, а при AllGenres -
+	// фильмы, относящиеся ко всем жанрам сразу. Идентификаторы не повторяются.
+	GenreIDs    []int
+	AllGenres   bool
+	TitlePrefix string
+	// Description ищется как подстрока без учёта регистра.
+	Description string
+}
+
+// where строит условие WHERE для колонок таблицы movies. Значения передаются
+// только параметрами, нумерация которых продолжает args.
+func (f MovieFilter) where(args []interface{}) (string, []interface{}) {
+	var conditions []string
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if !f.ReleasedFrom.IsZero() {
+		add("release_date >= ?", f.ReleasedFrom)
+	}
+	if !f.ReleasedTo.IsZero() {
+		add("release_date <= ?", f.ReleasedTo)
+	}
+	if f.MinRating != nil {
+		add("rating >= ?", *f.MinRating)
+	}
+	if f.MaxRating != nil {
+		add("rating <= ?", *f.MaxRating)
+	}
+	if len(f.ActorIDs) > 0 {
+		add("id IN (SELECT movie_id FROM movie_actors WHERE actor_id = ANY(?))", pq.Array(f.ActorIDs))
+	}
+	if len(f.GenreIDs) > 0 {
+		if f.AllGenres {
+			add(`id IN (
+				SELECT movie_id FROM movie_genres WHERE genre_id = ANY(?)
+				GROUP BY movie_id HAVING count(*) = cardinality(?::int[]))`, pq.Array(f.GenreIDs))
+		} else {
+			add("id IN (SELECT movie_id FROM movie_genres WHERE genre_id = ANY(?))", pq.Array(f.GenreIDs))
+		}
+	}
+	if f.TitlePrefix != "" {
+		add("title LIKE ?", escapeLike(f.TitlePrefix)+"%")
+	}
+	if f.Description != "" {
+		add("description ILIKE ?", "%"+escapeLike(f.Description)+"%")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// matches повторяет where для хранилища в памяти; cast - актёры фильма,
+// genres - его жанры. Фильм без даты выхода или рейтинга, как NULL в SQL, не
+// проходит ни одну границу по этому полю.
+func (f MovieFilter) matches(movie Movie, cast map[int]CastMember, genres map[int]struct{}) bool {
+	if !f.ReleasedFrom.IsZero() && (movie.ReleaseDate.IsZero() || movie.ReleaseDate.Before(f.ReleasedFrom)) {
+		return false
+	}
+	if !f.ReleasedTo.IsZero() && (movie.ReleaseDate.IsZero() || movie.ReleaseDate.After(f.ReleasedTo)) {
+		return false
+	}
+	if f.MinRating != nil && (movie.Rating == nil || *movie.Rating < *f.MinRating) {
+		return false
+	}
+	if f.MaxRating != nil && (movie.Rating == nil || *movie.Rating > *f.MaxRating) {
+		return false
+	}
+	if len(f.ActorIDs) > 0 {
+		found := false
+		for _, actorID := range f.ActorIDs {
+			if _, ok := cast[actorID]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.GenreIDs) > 0 {
+		found := 0
+		for _, genreID := range f.GenreIDs {
+			if _, ok := genres[genreID]; ok {
+				found++
+			}
+		}
+		if found == 0 || (f.AllGenres && found < len(f.GenreIDs)) {
+			return false
+		}
+	}
+	if !strings.HasPrefix(movie.Title, f.TitlePrefix) {
+		return false
+	}
+	if !strings.Contains(strings.ToLower(movie.Description), strings.ToLower(f.Description)) {
+		return false
+	}
+	return true
+}
+
+// escapeLike экранирует служебные символы шаблона LIKE.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Suggestion - вариант для автодополнения или подсказки «возможно, вы имели в виду».
+type Suggestion struct {
+	ID    int     `json:"id"`
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// withSimilarityThreshold выполняет fn в транзакции, где операторы pg_trgm %
+// и <% используют заданный порог сходства. Порог задаётся через set_config
+// с is_local = true, поэтому не влияет на другие соединения пула.
+func (p *Postgres) withSimilarityThreshold(threshold float64, fn func(q queryer) error) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	value := strconv.FormatFloat(threshold, 'f', -1, 64)
+	if _, err := tx.Exec(`
+		SELECT set_config('pg_trgm.similarity_threshold', $1, true),
+		       set_config('pg_trgm.word_similarity_threshold', $1, true)
+	`, value); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SearchMoviesFuzzy ищет фильмы, название которых похоже на titleFragment или
+// в которых снимался актёр с именем, похожим на actorName. Пустой аргумент не
+// участвует в поиске.
+func (p *Postgres) SearchMoviesFuzzy(titleFragment, actorName string, threshold float64, page Page) ([]Movie, PageInfo, error) {
+	query := `
+		SELECT DISTINCT m.id, m.title, m.description, m.release_date, m.rating
+		FROM movies m
+		LEFT JOIN movie_actors ma ON m.id = ma.movie_id
+		LEFT JOIN actors a ON ma.actor_id = a.id
+		WHERE ($1::text <> '' AND $1 <% m.title) OR ($2::text <> '' AND $2 <% a.name)
+	`
+
+	var movies []Movie
+	var info PageInfo
+	err := p.withSimilarityThreshold(threshold, func(q queryer) error {
+		var err error
+		movies, info, err = queryMovies(q, query, []interface{}{titleFragment, actorName}, sortByID, page)
+		return err
+	})
+
+	return movies, info, err
+}
+
+// SuggestActors возвращает актёров, имя которых начинается с text или похоже
+// на него, по убыванию сходства.
+func (p *Postgres) SuggestActors(text string, threshold float64, limit int) ([]Suggestion, error) {
+	query := `
+		SELECT id, name,
+		       CASE WHEN name ILIKE $2 THEN 1 ELSE word_similarity($1, name) END AS score
+		FROM actors
+		WHERE name ILIKE $2 OR $1 <% name
+		ORDER BY score DESC, name, id
+		LIMIT $3
+	`
+	return p.suggest(query, text, threshold, limit)
+}
+
+// SuggestMovies возвращает фильмы, название которых начинается с text или
+// похоже на него, по убыванию сходства.
+func (p *Postgres) SuggestMovies(text string, threshold float64, limit int) ([]Suggestion, error) {
+	query := `
+		SELECT id, title,
+		       CASE WHEN title ILIKE $2 THEN 1 ELSE word_similarity($1, title) END AS score
+		FROM movies
+		WHERE title ILIKE $2 OR $1 <% title
+		ORDER BY score DESC, title, id
+		LIMIT $3
+	`
+	return p.suggest(query, text, threshold, limit)
+}
+
+func (p *Postgres) suggest(query, text string, threshold float64, limit int) ([]Suggestion, error) {
+	var suggestions []Suggestion
+	err := p.withSimilarityThreshold(threshold, func(q queryer) error {
+		rows, err := q.Query(query, text, escapeLike(text)+"%", limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var suggestion Suggestion
+			if err := rows.Scan(&suggestion.ID, &suggestion.Text, &suggestion.Score); err != nil {
+				return err
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+		return rows.Err()
+	})
+
+	return suggestions, err
+}
+
+// trigrams разбивает строку на триграммы так же, как pg_trgm: слова в нижнем
+// регистре дополняются двумя пробелами слева и одним справа.
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// wordSimilarity приближает word_similarity из pg_trgm: доля триграмм text,
+// найденных в target.
+func wordSimilarity(text, target string) float64 {
+	textTrigrams := trigrams(text)
+	if len(textTrigrams) == 0 {
+		return 0
+	}
+
+	targetTrigrams := trigrams(target)
+	common := 0
+	for trigram := range textTrigrams {
+		if _, ok := targetTrigrams[trigram]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(textTrigrams))
+}
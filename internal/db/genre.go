@@ -0,0 +1,186 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GenreFacet - число фильмов выборки, относящихся к жанру.
+type GenreFacet struct {
+	Genre
+	Count int `json:"count"`
+}
+
+func (p *Postgres) AddGenre(genre Genre) (Genre, error) {
+	err := p.db.QueryRow(`INSERT INTO genres (name) VALUES ($1) RETURNING id`, genre.Name).Scan(&genre.ID)
+	if err != nil {
+		return Genre{}, convertError(err)
+	}
+
+	return genre, nil
+}
+
+func (p *Postgres) UpdateGenre(genre Genre) error {
+	result, err := p.db.Exec(`UPDATE genres SET name = $2 WHERE id = $1`, genre.ID, genre.Name)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return checkAffected(result)
+}
+
+func (p *Postgres) GetGenre(genreID int) (Genre, error) {
+	var genre Genre
+	err := p.db.QueryRow(`SELECT id, name FROM genres WHERE id = $1`, genreID).Scan(&genre.ID, &genre.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Genre{}, ErrNotFound
+	}
+	if err != nil {
+		return Genre{}, err
+	}
+
+	return genre, nil
+}
+
+func (p *Postgres) GetGenres() ([]Genre, error) {
+	return queryGenres(p.db, `SELECT id, name FROM genres ORDER BY name, id`)
+}
+
+func (p *Postgres) DeleteGenre(genreID int) error {
+	result, err := p.db.Exec(`DELETE FROM genres WHERE id = $1`, genreID)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return checkAffected(result)
+}
+
+func (p *Postgres) GetMovieGenres(movieID int) ([]Genre, error) {
+	if _, err := p.GetMovie(movieID); err != nil {
+		return nil, err
+	}
+	return queryGenres(p.db, movieGenresQuery, movieID)
+}
+
+// ReplaceMovieGenres приводит жанры фильма к genreIDs за одну транзакцию.
+func (p *Postgres) ReplaceMovieGenres(movieID int, genreIDs []int) ([]Genre, error) {
+	if err := checkGenreIDs(genreIDs); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM movie_genres WHERE movie_id = $1 AND NOT genre_id = ANY($2)`,
+		movieID, pq.Array(genreIDs)); err != nil {
+		return nil, convertError(err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO movie_genres (movie_id, genre_id)
+		SELECT $1, unnest($2::int[])
+		ON CONFLICT DO NOTHING
+	`, movieID, pq.Array(genreIDs)); err != nil {
+		return nil, convertError(err)
+	}
+
+	genres, err := queryGenres(tx, movieGenresQuery, movieID)
+	if err != nil {
+		return nil, err
+	}
+
+	return genres, tx.Commit()
+}
+
+// GetMovieGenreFacets считает фильмы, подходящие под filter, по жанрам.
+func (p *Postgres) GetMovieGenreFacets(filter MovieFilter) ([]GenreFacet, error) {
+	where, args := filter.where(nil)
+	return queryGenreFacets(p.db, `SELECT id FROM movies`+where, args)
+}
+
+// SearchMovieGenreFacets считает найденные полнотекстовым поиском фильмы по жанрам.
+func (p *Postgres) SearchMovieGenreFacets(text string) ([]GenreFacet, error) {
+	return queryGenreFacets(p.db, movieSearchQuery, []interface{}{text})
+}
+
+const movieGenresQuery = `
+	SELECT g.id, g.name
+	FROM genres g
+	INNER JOIN movie_genres mg ON mg.genre_id = g.id
+	WHERE mg.movie_id = $1
+	ORDER BY g.name, g.id
+`
+
+func queryGenres(q queryer, query string, args ...interface{}) ([]Genre, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []Genre{}
+	for rows.Next() {
+		var genre Genre
+		if err := rows.Scan(&genre.ID, &genre.Name); err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+
+	return genres, rows.Err()
+}
+
+// queryGenreFacets группирует по жанрам фильмы из base; base должен
+// возвращать колонку id фильма.
+func queryGenreFacets(q queryer, base string, args []interface{}) ([]GenreFacet, error) {
+	query := `
+		SELECT g.id, g.name, count(*)
+		FROM (` + base + `) AS t
+		INNER JOIN movie_genres mg ON mg.movie_id = t.id
+		INNER JOIN genres g ON g.id = mg.genre_id
+		GROUP BY g.id, g.name
+		ORDER BY count(*) DESC, g.name, g.id
+	`
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []GenreFacet{}
+	for rows.Next() {
+		var facet GenreFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+
+	return facets, rows.Err()
+}
+
+func checkGenreIDs(genreIDs []int) error {
+	seen := make(map[int]struct{}, len(genreIDs))
+	for _, genreID := range genreIDs {
+		if _, ok := seen[genreID]; ok {
+			return fmt.Errorf("%w: genre_id=%d", ErrDuplicateKey, genreID)
+		}
+		seen[genreID] = struct{}{}
+	}
+	return nil
+}
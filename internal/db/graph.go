@@ -0,0 +1,209 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var ErrNoPath = errors.New("no path between actors")
+
+// CoStar - актёр, снимавшийся вместе с заданным, и число общих фильмов.
+type CoStar struct {
+	Actor
+	SharedMovies int `json:"shared_movies"`
+}
+
+// PathStep - звено цепочки между актёрами: актёр и фильм, который связывает
+// его с актёром следующего звена. У последнего звена фильма нет.
+type PathStep struct {
+	Actor Actor  `json:"actor"`
+	Movie *Movie `json:"movie,omitempty"`
+}
+
+// ActorPath - кратчайшая цепочка «актёр - фильм - актёр» между двумя актёрами.
+type ActorPath struct {
+	Degrees int        `json:"degrees"`
+	Steps   []PathStep `json:"steps"`
+}
+
+// graphEdge - ребро, по которому поиск впервые дошёл до актёра: от актёра
+// from через фильм movieID. depth - число рёбер от начала поиска.
+type graphEdge struct {
+	from    int
+	movieID int
+	depth   int
+}
+
+// expandFunc возвращает актёров, снимавшихся с актёрами frontier и ещё не
+// попавших в visited, вместе с ребром, по которому до них дошли.
+type expandFunc func(frontier []int, visited map[int]graphEdge) (map[int]graphEdge, error)
+
+// shortestPath ищет кратчайший путь между актёрами двунаправленным поиском в
+// ширину не длиннее maxDepth рёбер. Каждый шаг расширяет меньший из двух
+// фронтов на один уровень, поэтому expand вызывается не больше maxDepth раз.
+// Возвращает id актёров и id фильмов между соседними актёрами.
+func shortestPath(from, to, maxDepth int, expand expandFunc) ([]int, []int, error) {
+	if from == to {
+		return []int{from}, nil, nil
+	}
+
+	forward := map[int]graphEdge{from: {}}
+	backward := map[int]graphEdge{to: {}}
+	forwardFront, backwardFront := []int{from}, []int{to}
+
+	for depth := 0; depth < maxDepth; depth++ {
+		expandForward := len(forwardFront) <= len(backwardFront)
+		visited, other, front := forward, backward, forwardFront
+		if !expandForward {
+			visited, other, front = backward, forward, backwardFront
+		}
+
+		next, err := expand(front, visited)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(next) == 0 {
+			return nil, nil, ErrNoPath
+		}
+
+		meet, best := 0, -1
+		for _, actorID := range sortedKeys(next) {
+			visited[actorID] = next[actorID]
+			if edge, ok := other[actorID]; ok && (best < 0 || edge.depth < best) {
+				meet, best = actorID, edge.depth
+			}
+		}
+		if best >= 0 {
+			actors, movies := joinPath(meet, forward, backward)
+			return actors, movies, nil
+		}
+
+		if expandForward {
+			forwardFront = sortedKeys(next)
+		} else {
+			backwardFront = sortedKeys(next)
+		}
+	}
+
+	return nil, nil, ErrNoPath
+}
+
+// joinPath склеивает путь от начала прямого поиска до meet и от meet до
+// начала обратного поиска.
+func joinPath(meet int, forward, backward map[int]graphEdge) ([]int, []int) {
+	actors := []int{meet}
+	var movies []int
+	for actorID := meet; forward[actorID].depth > 0; actorID = forward[actorID].from {
+		actors = append(actors, forward[actorID].from)
+		movies = append(movies, forward[actorID].movieID)
+	}
+	for i, j := 0, len(actors)-1; i < j; i, j = i+1, j-1 {
+		actors[i], actors[j] = actors[j], actors[i]
+	}
+	for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+		movies[i], movies[j] = movies[j], movies[i]
+	}
+
+	for actorID := meet; backward[actorID].depth > 0; actorID = backward[actorID].from {
+		actors = append(actors, backward[actorID].from)
+		movies = append(movies, backward[actorID].movieID)
+	}
+
+	return actors, movies
+}
+
+// buildActorPath загружает актёров и фильмы найденного пути.
+func buildActorPath(actorIDs, movieIDs []int, getActor func(int) (Actor, error), getMovie func(int) (Movie, error)) (ActorPath, error) {
+	path := ActorPath{Degrees: len(movieIDs), Steps: make([]PathStep, 0, len(actorIDs))}
+	for i, actorID := range actorIDs {
+		actor, err := getActor(actorID)
+		if err != nil {
+			return ActorPath{}, err
+		}
+		step := PathStep{Actor: actor}
+		if i < len(movieIDs) {
+			movie, err := getMovie(movieIDs[i])
+			if err != nil {
+				return ActorPath{}, err
+			}
+			step.Movie = &movie
+		}
+		path.Steps = append(path.Steps, step)
+	}
+	return path, nil
+}
+
+// GetCoStars возвращает до limit актёров, чаще всего снимавшихся с actorID.
+func (p *Postgres) GetCoStars(actorID, limit int) ([]CoStar, error) {
+	if _, err := p.GetActor(actorID); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.db.Query(`
+		SELECT a.id, a.name, a.gender, a.birthdate, count(*) AS shared_movies
+		FROM movie_actors ma
+		INNER JOIN movie_actors co ON co.movie_id = ma.movie_id AND co.actor_id <> ma.actor_id
+		INNER JOIN actors a ON a.id = co.actor_id
+		WHERE ma.actor_id = $1
+		GROUP BY a.id
+		ORDER BY shared_movies DESC, a.name, a.id
+		LIMIT $2
+	`, actorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coStars := []CoStar{}
+	for rows.Next() {
+		var coStar CoStar
+		if err := rows.Scan(append(coStar.fields(), &coStar.SharedMovies)...); err != nil {
+			return nil, err
+		}
+		coStars = append(coStars, coStar)
+	}
+
+	return coStars, rows.Err()
+}
+
+// GetActorPath ищет кратчайшую цепочку между актёрами не длиннее maxDepth
+// фильмов. Один уровень поиска - один запрос по индексам movie_actors.
+func (p *Postgres) GetActorPath(fromID, toID, maxDepth int) (ActorPath, error) {
+	for _, actorID := range []int{fromID, toID} {
+		if _, err := p.GetActor(actorID); err != nil {
+			return ActorPath{}, err
+		}
+	}
+
+	actorIDs, movieIDs, err := shortestPath(fromID, toID, maxDepth, func(frontier []int, visited map[int]graphEdge) (map[int]graphEdge, error) {
+		depth := visited[frontier[0]].depth + 1
+		rows, err := p.db.Query(`
+			SELECT DISTINCT ON (co.actor_id) co.actor_id, ma.actor_id, ma.movie_id
+			FROM movie_actors ma
+			INNER JOIN movie_actors co ON co.movie_id = ma.movie_id
+			WHERE ma.actor_id = ANY($1) AND NOT co.actor_id = ANY($2)
+			ORDER BY co.actor_id, ma.actor_id, ma.movie_id
+		`, pq.Array(frontier), pq.Array(sortedKeys(visited)))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		next := make(map[int]graphEdge)
+		for rows.Next() {
+			var actorID int
+			edge := graphEdge{depth: depth}
+			if err := rows.Scan(&actorID, &edge.from, &edge.movieID); err != nil {
+				return nil, err
+			}
+			next[actorID] = edge
+		}
+		return next, rows.Err()
+	})
+	if err != nil {
+		return ActorPath{}, err
+	}
+
+	return buildActorPath(actorIDs, movieIDs, p.GetActor, p.GetMovie)
+}
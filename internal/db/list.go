@@ -0,0 +1,317 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WatchlistItem - фильм из списка «посмотреть позже».
+type WatchlistItem struct {
+	Movie
+	AddedAt time.Time `json:"added_at"`
+}
+
+// WatchedEntry - запись журнала просмотров с личной оценкой. В отличие от
+// отзыва, оценка из журнала видна только владельцу и не влияет на рейтинг.
+type WatchedEntry struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Movie     Movie     `json:"movie"`
+	WatchedOn time.Time `json:"watched_on"`
+	Score     *int      `json:"score"`
+}
+
+// MovieList - именованный список фильмов пользователя. Публичный список
+// доступен всем по Slug.
+type MovieList struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Slug        string    `json:"slug"`
+	Public      bool      `json:"public"`
+	CreatedAt   time.Time `json:"created_at"`
+	MovieCount  int       `json:"movie_count"`
+}
+
+// MovieListDetail - список вместе с фильмами в порядке добавления.
+type MovieListDetail struct {
+	MovieList
+	Movies []Movie `json:"movies"`
+}
+
+// timestampLayout сохраняет порядок при сравнении строк и точность Postgres.
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+var (
+	sortByAddedAt   = Sort{Column: "added_at", Desc: true}
+	sortByWatchedOn = Sort{Column: "watched_on", Desc: true}
+)
+
+func watchlistKey(item WatchlistItem) (string, int) {
+	return item.AddedAt.UTC().Format(timestampLayout), item.ID
+}
+
+func watchedKey(entry WatchedEntry) (string, int) {
+	return entry.WatchedOn.Format(time.DateOnly), entry.ID
+}
+
+func (p *Postgres) GetWatchlist(userID int, page Page) ([]WatchlistItem, PageInfo, error) {
+	query := `
+		SELECT m.id, m.title, m.description, m.release_date, m.rating, wl.added_at
+		FROM watchlist wl
+		INNER JOIN movies m ON m.id = wl.movie_id
+		WHERE wl.user_id = $1
+	`
+
+	return queryPage(p.db, query, []interface{}{userID}, sortByAddedAt, page, watchlistKey,
+		func(rows *sql.Rows, item *WatchlistItem) error {
+			return rows.Scan(append(item.fields(), &item.AddedAt)...)
+		})
+}
+
+// AddToWatchlist добавляет фильм в список «посмотреть позже»; added сообщает,
+// что фильма в списке ещё не было.
+func (p *Postgres) AddToWatchlist(userID, movieID int) (bool, error) {
+	result, err := p.db.Exec(`
+		INSERT INTO watchlist (user_id, movie_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, movieID)
+	if err != nil {
+		return false, convertError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (p *Postgres) RemoveFromWatchlist(userID, movieID int) error {
+	result, err := p.db.Exec(`DELETE FROM watchlist WHERE user_id = $1 AND movie_id = $2`, userID, movieID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// watchedQuery переименовывает m.id, чтобы t.id в paginateQuery был id записи журнала.
+const watchedQuery = `
+	SELECT w.id, w.watched_on, w.score, m.id AS movie_id, m.title, m.description, m.release_date, m.rating
+	FROM watched w
+	INNER JOIN movies m ON m.id = w.movie_id
+`
+
+func scanWatched(row interface{ Scan(...interface{}) error }, entry *WatchedEntry) error {
+	return row.Scan(append([]interface{}{&entry.ID, &entry.WatchedOn, &entry.Score}, entry.Movie.fields()...)...)
+}
+
+// GetWatched возвращает журнал просмотров, последние просмотры первыми.
+func (p *Postgres) GetWatched(userID int, page Page) ([]WatchedEntry, PageInfo, error) {
+	return queryPage(p.db, watchedQuery+` WHERE w.user_id = $1`, []interface{}{userID}, sortByWatchedOn, page, watchedKey,
+		func(rows *sql.Rows, entry *WatchedEntry) error {
+			return scanWatched(rows, entry)
+		})
+}
+
+// AddWatched добавляет запись в журнал просмотров. Фильм задаётся entry.Movie.ID.
+func (p *Postgres) AddWatched(entry WatchedEntry) (WatchedEntry, error) {
+	if err := checkWatched(entry); err != nil {
+		return WatchedEntry{}, err
+	}
+
+	var id int
+	err := p.db.QueryRow(`
+		INSERT INTO watched (user_id, movie_id, watched_on, score)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, entry.UserID, entry.Movie.ID, entry.WatchedOn, entry.Score).Scan(&id)
+	if err != nil {
+		return WatchedEntry{}, convertError(err)
+	}
+
+	var saved WatchedEntry
+	err = scanWatched(p.db.QueryRow(watchedQuery+` WHERE w.id = $1`, id), &saved)
+	return saved, err
+}
+
+func (p *Postgres) DeleteWatched(userID, entryID int) error {
+	result, err := p.db.Exec(`DELETE FROM watched WHERE id = $1 AND user_id = $2`, entryID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+const movieListQuery = `
+	SELECT l.id, l.user_id, l.name, l.description, l.slug, l.public, l.created_at, count(li.movie_id)
+	FROM movie_lists l
+	LEFT JOIN movie_list_items li ON li.list_id = l.id
+`
+
+func scanMovieList(row interface{ Scan(...interface{}) error }, list *MovieList) error {
+	return row.Scan(&list.ID, &list.UserID, &list.Name, &list.Description, &list.Slug, &list.Public,
+		&list.CreatedAt, &list.MovieCount)
+}
+
+func (p *Postgres) GetLists(userID int) ([]MovieList, error) {
+	rows, err := p.db.Query(movieListQuery+`
+		WHERE l.user_id = $1
+		GROUP BY l.id
+		ORDER BY l.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []MovieList{}
+	for rows.Next() {
+		var list MovieList
+		if err := scanMovieList(rows, &list); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+// queryListDetail читает список, подходящий под условие where, вместе с фильмами.
+func queryListDetail(q queryer, where string, args ...interface{}) (MovieListDetail, error) {
+	var detail MovieListDetail
+	err := scanMovieList(q.QueryRow(movieListQuery+` WHERE `+where+` GROUP BY l.id`, args...), &detail.MovieList)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MovieListDetail{}, ErrNotFound
+	}
+	if err != nil {
+		return MovieListDetail{}, err
+	}
+
+	rows, err := q.Query(`
+		SELECT m.id, m.title, m.description, m.release_date, m.rating
+		FROM movie_list_items li
+		INNER JOIN movies m ON m.id = li.movie_id
+		WHERE li.list_id = $1
+		ORDER BY li.added_at, m.id
+	`, detail.ID)
+	if err != nil {
+		return MovieListDetail{}, err
+	}
+	defer rows.Close()
+
+	detail.Movies = []Movie{}
+	for rows.Next() {
+		var movie Movie
+		if err := rows.Scan(movie.fields()...); err != nil {
+			return MovieListDetail{}, err
+		}
+		detail.Movies = append(detail.Movies, movie)
+	}
+
+	return detail, rows.Err()
+}
+
+func (p *Postgres) AddList(list MovieList) (MovieListDetail, error) {
+	var id int
+	err := p.db.QueryRow(`
+		INSERT INTO movie_lists (user_id, name, description, slug, public)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, list.UserID, list.Name, list.Description, list.Slug, list.Public).Scan(&id)
+	if err != nil {
+		return MovieListDetail{}, convertError(err)
+	}
+
+	return queryListDetail(p.db, `l.id = $1`, id)
+}
+
+func (p *Postgres) GetList(userID, listID int) (MovieListDetail, error) {
+	return queryListDetail(p.db, `l.id = $1 AND l.user_id = $2`, listID, userID)
+}
+
+// UpdateList заменяет название, описание, slug и видимость списка.
+func (p *Postgres) UpdateList(list MovieList) (MovieListDetail, error) {
+	result, err := p.db.Exec(`
+		UPDATE movie_lists
+		SET name = $3, description = $4, slug = $5, public = $6
+		WHERE id = $1 AND user_id = $2
+	`, list.ID, list.UserID, list.Name, list.Description, list.Slug, list.Public)
+	if err != nil {
+		return MovieListDetail{}, convertError(err)
+	}
+	if err := checkAffected(result); err != nil {
+		return MovieListDetail{}, err
+	}
+
+	return p.GetList(list.UserID, list.ID)
+}
+
+func (p *Postgres) DeleteList(userID, listID int) error {
+	result, err := p.db.Exec(`DELETE FROM movie_lists WHERE id = $1 AND user_id = $2`, listID, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (p *Postgres) AddListMovie(userID, listID, movieID int) (MovieListDetail, error) {
+	return p.changeListMovies(userID, listID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO movie_list_items (list_id, movie_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, listID, movieID)
+		return convertError(err)
+	})
+}
+
+func (p *Postgres) RemoveListMovie(userID, listID, movieID int) (MovieListDetail, error) {
+	return p.changeListMovies(userID, listID, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM movie_list_items WHERE list_id = $1 AND movie_id = $2`, listID, movieID)
+		if err != nil {
+			return err
+		}
+		return checkAffected(result)
+	})
+}
+
+// changeListMovies блокирует список пользователя, меняет его фильмы и
+// возвращает итоговый список.
+func (p *Postgres) changeListMovies(userID, listID int, change func(tx *sql.Tx) error) (MovieListDetail, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return MovieListDetail{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`SELECT id FROM movie_lists WHERE id = $1 AND user_id = $2 FOR UPDATE`, listID, userID).Scan(&listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MovieListDetail{}, ErrNotFound
+	}
+	if err != nil {
+		return MovieListDetail{}, err
+	}
+
+	if err := change(tx); err != nil {
+		return MovieListDetail{}, err
+	}
+
+	detail, err := queryListDetail(tx, `l.id = $1`, listID)
+	if err != nil {
+		return MovieListDetail{}, err
+	}
+
+	return detail, tx.Commit()
+}
+
+// GetPublicList возвращает публичный список по slug; закрытый список не
+// отличается от несуществующего.
+func (p *Postgres) GetPublicList(slug string) (MovieListDetail, error) {
+	return queryListDetail(p.db, `l.slug = $1 AND l.public`, slug)
+}
+
+func checkWatched(entry WatchedEntry) error {
+	if entry.Score == nil {
+		return nil
+	}
+	return checkScore(*entry.Score)
+}
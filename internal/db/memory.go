@@ -0,0 +1,2130 @@
+package db
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxGenreNameLength = 100
+	maxListNameLength  = 100
+	maxListSlugLength  = 64
+	maxUsernameLength  = 64
+)
+
+// Memory хранит данные в памяти процесса и повторяет поведение схемы из миграций:
+// ограничения длины колонок, внешние ключи movie_actors и каскадное удаление.
+type Memory struct {
+	mu            sync.RWMutex
+	actors        map[int]Actor
+	movies        map[int]Movie
+	movieActors   map[int]map[int]CastMember
+	genres        map[int]Genre
+	movieGenres   map[int]map[int]struct{}
+	movieCrew     map[int][]CrewMember
+	reviews       map[int]Review
+	votes         map[int]RatingStats
+	watchlist     map[int]map[int]time.Time
+	watched       map[int]WatchedEntry
+	lists         map[int]MovieList
+	listItems     map[int]map[int]time.Time
+	similarities  map[int][]movieSimilarity
+	users         map[int]User
+	tokens        map[string]Token
+	nextActorID   int
+	nextMovieID   int
+	nextGenreID   int
+	nextReviewID  int
+	nextWatchedID int
+	nextListID    int
+	nextUserID    int
+	nextTokenID   int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		actors:        make(map[int]Actor),
+		movies:        make(map[int]Movie),
+		movieActors:   make(map[int]map[int]CastMember),
+		genres:        make(map[int]Genre),
+		movieGenres:   make(map[int]map[int]struct{}),
+		movieCrew:     make(map[int][]CrewMember),
+		reviews:       make(map[int]Review),
+		votes:         make(map[int]RatingStats),
+		watchlist:     make(map[int]map[int]time.Time),
+		watched:       make(map[int]WatchedEntry),
+		lists:         make(map[int]MovieList),
+		listItems:     make(map[int]map[int]time.Time),
+		similarities:  make(map[int][]movieSimilarity),
+		users:         make(map[int]User),
+		tokens:        make(map[string]Token),
+		nextActorID:   1,
+		nextMovieID:   1,
+		nextGenreID:   1,
+		nextReviewID:  1,
+		nextWatchedID: 1,
+		nextListID:    1,
+		nextUserID:    1,
+		nextTokenID:   1,
+	}
+}
+
+func (m *Memory) AddActor(actor Actor) (Actor, error) {
+	if err := checkActorColumns(actor); err != nil {
+		return Actor{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actor.Id = m.nextActorID
+	actor.Birthdate = truncateBirthdate(actor.Birthdate)
+	m.actors[actor.Id] = actor
+	m.nextActorID++
+
+	return actor, nil
+}
+
+func (m *Memory) UpdateActor(actorID int, patch ActorPatch) (Actor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actor, ok := m.actors[actorID]
+	if !ok {
+		return Actor{}, ErrNotFound
+	}
+
+	apply(&actor.Name, patch.Name)
+	apply(&actor.Gender, patch.Gender)
+	switch {
+	case patch.Birthdate.Null:
+		actor.Birthdate = nil
+	case patch.Birthdate.Set:
+		actor.Birthdate = truncateBirthdate(&patch.Birthdate.Value)
+	}
+	if err := checkActorColumns(actor); err != nil {
+		return Actor{}, err
+	}
+	m.actors[actorID] = actor
+
+	return actor, nil
+}
+
+func (m *Memory) ReplaceActor(actor Actor) (Actor, error) {
+	if err := checkActorColumns(actor); err != nil {
+		return Actor{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.actors[actor.Id]; !ok {
+		return Actor{}, ErrNotFound
+	}
+	actor.Birthdate = truncateBirthdate(actor.Birthdate)
+	m.actors[actor.Id] = actor
+
+	return actor, nil
+}
+
+func (m *Memory) GetActor(actorID int) (Actor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	actor, ok := m.actors[actorID]
+	if !ok {
+		return Actor{}, ErrNotFound
+	}
+
+	return actor, nil
+}
+
+func (m *Memory) DeleteActor(actorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.actors[actorID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.actors, actorID)
+	for _, cast := range m.movieActors {
+		delete(cast, actorID)
+	}
+	for movieID, crew := range m.movieCrew {
+		m.movieCrew[movieID] = slices.DeleteFunc(crew, func(member CrewMember) bool {
+			return member.PersonID == actorID
+		})
+	}
+
+	return nil
+}
+
+func (m *Memory) GetActors(page Page) ([]Actor, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var actors []Actor
+	for _, id := range sortedKeys(m.actors) {
+		actors = append(actors, m.actors[id])
+	}
+
+	return paginate(actors, sortByID, page, actorKey)
+}
+
+func (m *Memory) AddMovie(movie Movie) (Movie, error) {
+	if err := checkMovieColumns(movie); err != nil {
+		return Movie{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie.ID = m.nextMovieID
+	movie.ReleaseDate = truncateDate(movie.ReleaseDate)
+	m.movies[movie.ID] = movie
+	m.nextMovieID++
+
+	return movie, nil
+}
+
+func (m *Memory) UpdateMovie(movieID int, patch MoviePatch) (Movie, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok {
+		return Movie{}, ErrNotFound
+	}
+
+	apply(&movie.Title, patch.Title)
+	apply(&movie.Description, patch.Description)
+	apply(&movie.ReleaseDate, patch.ReleaseDate)
+	movie.ReleaseDate = truncateDate(movie.ReleaseDate)
+	if err := checkMovieColumns(movie); err != nil {
+		return Movie{}, err
+	}
+	m.movies[movieID] = movie
+
+	return movie, nil
+}
+
+func (m *Memory) ReplaceMovie(movie Movie) (Movie, error) {
+	if err := checkMovieColumns(movie); err != nil {
+		return Movie{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.movies[movie.ID]
+	if !ok {
+		return Movie{}, ErrNotFound
+	}
+	movie.ReleaseDate = truncateDate(movie.ReleaseDate)
+	movie.Rating = stored.Rating
+	m.movies[movie.ID] = movie
+
+	return movie, nil
+}
+
+func (m *Memory) GetMovie(movieID int) (Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok {
+		return Movie{}, ErrNotFound
+	}
+
+	return movie, nil
+}
+
+func (m *Memory) DeleteMovie(movieID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.movies, movieID)
+	delete(m.movieActors, movieID)
+	delete(m.movieGenres, movieID)
+	delete(m.movieCrew, movieID)
+	delete(m.votes, movieID)
+	for id, review := range m.reviews {
+		if review.MovieID == movieID {
+			delete(m.reviews, id)
+		}
+	}
+	for _, watchlist := range m.watchlist {
+		delete(watchlist, movieID)
+	}
+	for id, entry := range m.watched {
+		if entry.Movie.ID == movieID {
+			delete(m.watched, id)
+		}
+	}
+	for _, items := range m.listItems {
+		delete(items, movieID)
+	}
+	delete(m.similarities, movieID)
+	for id, similar := range m.similarities {
+		m.similarities[id] = slices.DeleteFunc(similar, func(s movieSimilarity) bool {
+			return s.similarID == movieID
+		})
+	}
+
+	return nil
+}
+
+func (m *Memory) AddMovieActor(movieID, actorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return fmt.Errorf("%w: movie %d does not exist", ErrForeignKeyViolation, movieID)
+	}
+	if _, ok := m.actors[actorID]; !ok {
+		return fmt.Errorf("%w: actor %d does not exist", ErrForeignKeyViolation, actorID)
+	}
+
+	cast, ok := m.movieActors[movieID]
+	if !ok {
+		cast = make(map[int]CastMember)
+		m.movieActors[movieID] = cast
+	}
+	if _, ok := cast[actorID]; ok {
+		return fmt.Errorf("%w: (movie_id, actor_id)=(%d, %d)", ErrDuplicateKey, movieID, actorID)
+	}
+	cast[actorID] = CastMember{ActorID: actorID}
+
+	return nil
+}
+
+func (m *Memory) GetMovieCast(movieID int) ([]CastMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	return m.castOf(movieID), nil
+}
+
+func (m *Memory) ReplaceMovieCast(movieID int, cast []CastMember) ([]CastMember, error) {
+	if err := checkCast(cast); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	members := make(map[int]CastMember, len(cast))
+	for _, member := range cast {
+		if _, ok := m.actors[member.ActorID]; !ok {
+			return nil, fmt.Errorf("%w: actor %d does not exist", ErrForeignKeyViolation, member.ActorID)
+		}
+		member.Name = ""
+		members[member.ActorID] = member
+	}
+	m.movieActors[movieID] = members
+
+	return m.castOf(movieID), nil
+}
+
+func (m *Memory) RemoveMovieActor(movieID, actorID int) ([]CastMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	if _, ok := m.movieActors[movieID][actorID]; !ok {
+		return nil, ErrNotFound
+	}
+	delete(m.movieActors[movieID], actorID)
+
+	return m.castOf(movieID), nil
+}
+
+func (m *Memory) GetMoviesWithSorting(filter MovieFilter, orderBy, sortOrder string, page Page) ([]Movie, PageInfo, error) {
+	order, err := movieSort(orderBy, sortOrder)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []Movie
+	for _, id := range sortedKeys(m.movies) {
+		if filter.matches(m.movies[id], m.movieActors[id], m.movieGenres[id]) {
+			movies = append(movies, m.movies[id])
+		}
+	}
+
+	key := movieKey(order.Column)
+	sort.Slice(movies, func(i, j int) bool {
+		iValue, iID := key(movies[i])
+		jValue, jID := key(movies[j])
+		return compareKeys(order, iValue, iID, jValue, jID) < 0
+	})
+
+	return paginate(movies, order, page, key)
+}
+
+func (m *Memory) SearchMoviesByActorName(actorName string, page Page) ([]Movie, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []Movie
+	for _, movieID := range sortedKeys(m.movies) {
+		for _, actorID := range sortedKeys(m.movieActors[movieID]) {
+			if m.actors[actorID].Name == actorName {
+				movies = append(movies, m.movies[movieID])
+			}
+		}
+	}
+
+	return paginate(movies, sortByID, page, movieKey(sortByID.Column))
+}
+
+func (m *Memory) SearchMoviesByTitleOrActorName(titleFragment, actorNameFragment string, page Page) ([]Movie, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []Movie
+	for _, id := range sortedKeys(m.movies) {
+		movie := m.movies[id]
+		if strings.Contains(movie.Title, titleFragment) || m.hasActorLike(id, actorNameFragment) {
+			movies = append(movies, movie)
+		}
+	}
+
+	return paginate(movies, sortByID, page, movieKey(sortByID.Column))
+}
+
+func (m *Memory) GetMoviesByActorName(actorName string, page Page) ([]MovieCredit, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var credits []MovieCredit
+	for _, movieID := range sortedKeys(m.movies) {
+		for _, actorID := range sortedKeys(m.movieActors[movieID]) {
+			if m.actors[actorID].Name == actorName {
+				credits = append(credits, m.creditOf(movieID, actorID))
+			}
+		}
+	}
+
+	return paginate(credits, sortByID, page, movieCreditKey)
+}
+
+func (m *Memory) GetMoviesByActorID(actorID int, page Page) ([]MovieCredit, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var credits []MovieCredit
+	for _, movieID := range sortedKeys(m.movies) {
+		if _, ok := m.movieActors[movieID][actorID]; ok {
+			credits = append(credits, m.creditOf(movieID, actorID))
+		}
+	}
+
+	return paginate(credits, sortByID, page, movieCreditKey)
+}
+
+func (m *Memory) GetActorFilmography(actorID int) ([]MovieCredit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credits := []MovieCredit{}
+	for _, movieID := range sortedKeys(m.movies) {
+		if _, ok := m.movieActors[movieID][actorID]; ok {
+			credits = append(credits, m.creditOf(movieID, actorID))
+		}
+	}
+
+	sort.SliceStable(credits, func(i, j int) bool {
+		return credits[i].ReleaseDate.After(credits[j].ReleaseDate)
+	})
+	return credits, nil
+}
+
+// SearchMovies приближает полнотекстовый поиск Postgres: все слова запроса
+// должны встретиться в названии или описании фильма либо в имени одного актёра.
+func (m *Memory) SearchMovies(text string, page Page) ([]MovieSearchHit, PageInfo, error) {
+	terms := searchTerms(text)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hits := m.searchMovieHits(terms)
+	sortHits(hits, movieHitKey)
+	return paginate(hits, sortByRank, page, movieHitKey)
+}
+
+// searchMovieHits находит фильмы для SearchMovies без сортировки; вызывающий
+// держит m.mu.
+func (m *Memory) searchMovieHits(terms []string) []MovieSearchHit {
+	var hits []MovieSearchHit
+	for _, id := range sortedKeys(m.movies) {
+		movie := m.movies[id]
+		hit := MovieSearchHit{Movie: movie}
+
+		if containsAll(movie.Title+" "+movie.Description, terms) {
+			for _, term := range terms {
+				if containsFold(movie.Title, term) {
+					hit.Rank += 1.0 / float64(len(terms))
+				} else {
+					hit.Rank += 0.4 / float64(len(terms))
+				}
+			}
+		}
+		actorRank := 0.0
+		for _, actorID := range sortedKeys(m.movieActors[id]) {
+			name := m.actors[actorID].Name
+			if containsAll(name, terms) {
+				actorRank = 0.1
+				hit.Highlights.Actors = append(hit.Highlights.Actors, highlight(name, terms))
+			}
+		}
+		if hit.Rank == 0 && actorRank == 0 {
+			continue
+		}
+		hit.Rank += actorRank
+
+		hit.Highlights.Title = highlight(movie.Title, terms)
+		hit.Highlights.Description = highlight(movie.Description, terms)
+		hits = append(hits, hit)
+	}
+
+	return hits
+}
+
+func (m *Memory) AddGenre(genre Genre) (Genre, error) {
+	if err := checkGenreColumns(genre); err != nil {
+		return Genre{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkGenreName(genre); err != nil {
+		return Genre{}, err
+	}
+	genre.ID = m.nextGenreID
+	m.genres[genre.ID] = genre
+	m.nextGenreID++
+
+	return genre, nil
+}
+
+func (m *Memory) UpdateGenre(genre Genre) error {
+	if err := checkGenreColumns(genre); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[genre.ID]; !ok {
+		return ErrNotFound
+	}
+	if err := m.checkGenreName(genre); err != nil {
+		return err
+	}
+	m.genres[genre.ID] = genre
+
+	return nil
+}
+
+func (m *Memory) GetGenre(genreID int) (Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	genre, ok := m.genres[genreID]
+	if !ok {
+		return Genre{}, ErrNotFound
+	}
+
+	return genre, nil
+}
+
+func (m *Memory) GetGenres() ([]Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	genres := []Genre{}
+	for _, id := range sortedKeys(m.genres) {
+		genres = append(genres, m.genres[id])
+	}
+	sortGenres(genres)
+
+	return genres, nil
+}
+
+func (m *Memory) DeleteGenre(genreID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[genreID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.genres, genreID)
+	for _, genres := range m.movieGenres {
+		delete(genres, genreID)
+	}
+
+	return nil
+}
+
+func (m *Memory) GetMovieGenres(movieID int) ([]Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	return m.genresOf(movieID), nil
+}
+
+func (m *Memory) ReplaceMovieGenres(movieID int, genreIDs []int) ([]Genre, error) {
+	if err := checkGenreIDs(genreIDs); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	genres := make(map[int]struct{}, len(genreIDs))
+	for _, genreID := range genreIDs {
+		if _, ok := m.genres[genreID]; !ok {
+			return nil, fmt.Errorf("%w: genre %d does not exist", ErrForeignKeyViolation, genreID)
+		}
+		genres[genreID] = struct{}{}
+	}
+	m.movieGenres[movieID] = genres
+
+	return m.genresOf(movieID), nil
+}
+
+func (m *Memory) GetMovieGenreFacets(filter MovieFilter) ([]GenreFacet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movieIDs []int
+	for _, id := range sortedKeys(m.movies) {
+		if filter.matches(m.movies[id], m.movieActors[id], m.movieGenres[id]) {
+			movieIDs = append(movieIDs, id)
+		}
+	}
+
+	return m.genreFacets(movieIDs), nil
+}
+
+func (m *Memory) SearchMovieGenreFacets(text string) ([]GenreFacet, error) {
+	terms := searchTerms(text)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movieIDs []int
+	for _, hit := range m.searchMovieHits(terms) {
+		movieIDs = append(movieIDs, hit.ID)
+	}
+
+	return m.genreFacets(movieIDs), nil
+}
+
+func (m *Memory) GetMovieCrew(movieID int) ([]CrewMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	return m.crewOf(movieID), nil
+}
+
+func (m *Memory) ReplaceMovieCrew(movieID int, crew []CrewMember) ([]CrewMember, error) {
+	if err := checkCrew(crew); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	members := make([]CrewMember, 0, len(crew))
+	for _, member := range crew {
+		if _, ok := m.actors[member.PersonID]; !ok {
+			return nil, fmt.Errorf("%w: person %d does not exist", ErrForeignKeyViolation, member.PersonID)
+		}
+		members = append(members, CrewMember{PersonID: member.PersonID, Job: member.Job})
+	}
+	m.movieCrew[movieID] = members
+
+	return m.crewOf(movieID), nil
+}
+
+func (m *Memory) RemoveMovieCrewMember(movieID, personID int, job string) ([]CrewMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+	crew := m.movieCrew[movieID]
+	remaining := slices.DeleteFunc(slices.Clone(crew), func(member CrewMember) bool {
+		return member.PersonID == personID && (job == "" || member.Job == job)
+	})
+	if len(remaining) == len(crew) {
+		return nil, ErrNotFound
+	}
+	m.movieCrew[movieID] = remaining
+
+	return m.crewOf(movieID), nil
+}
+
+func (m *Memory) GetPersonCrewCredits(personID int) ([]CrewCredit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credits := []CrewCredit{}
+	for _, movieID := range sortedKeys(m.movies) {
+		for _, member := range m.crewOf(movieID) {
+			if member.PersonID == personID {
+				credits = append(credits, CrewCredit{Movie: m.movies[movieID], Job: member.Job})
+			}
+		}
+	}
+
+	sort.SliceStable(credits, func(i, j int) bool {
+		return credits[i].ReleaseDate.After(credits[j].ReleaseDate)
+	})
+	return credits, nil
+}
+
+func (m *Memory) SearchMoviesByCrew(name, job string, page Page) ([]Movie, PageInfo, error) {
+	return m.searchMoviesByCrew(job, page, func(personName string) bool {
+		return personName == name
+	})
+}
+
+func (m *Memory) SearchMoviesByCrewFuzzy(name, job string, threshold float64, page Page) ([]Movie, PageInfo, error) {
+	return m.searchMoviesByCrew(job, page, func(personName string) bool {
+		return wordSimilarity(name, personName) >= threshold
+	})
+}
+
+func (m *Memory) searchMoviesByCrew(job string, page Page, match func(name string) bool) ([]Movie, PageInfo, error) {
+	if err := checkJob(job); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []Movie
+	for _, movieID := range sortedKeys(m.movies) {
+		for _, member := range m.movieCrew[movieID] {
+			if (job == "" || member.Job == job) && match(m.actors[member.PersonID].Name) {
+				movies = append(movies, m.movies[movieID])
+				break
+			}
+		}
+	}
+
+	return paginate(movies, sortByID, page, movieKey(sortByID.Column))
+}
+
+func (m *Memory) SaveReview(review Review, prior RatingPrior) (Review, bool, error) {
+	if err := checkReview(review); err != nil {
+		return Review{}, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.movies[review.MovieID]; !ok {
+		return Review{}, false, ErrNotFound
+	}
+	if _, ok := m.users[review.UserID]; !ok {
+		return Review{}, false, fmt.Errorf("%w: user %d does not exist", ErrForeignKeyViolation, review.UserID)
+	}
+
+	now := time.Now()
+	stored, ok := m.userReview(review.MovieID, review.UserID)
+	if ok {
+		stored.Score, stored.Body, stored.UpdatedAt = review.Score, review.Body, now
+	} else {
+		stored = Review{
+			ID:        m.nextReviewID,
+			MovieID:   review.MovieID,
+			UserID:    review.UserID,
+			Score:     review.Score,
+			Body:      review.Body,
+			Status:    ReviewPublished,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		m.nextReviewID++
+	}
+	m.reviews[stored.ID] = stored
+	m.recalculateRating(review.MovieID, prior)
+
+	return m.reviewOf(stored.ID), !ok, nil
+}
+
+func (m *Memory) GetUserReview(movieID, userID int) (Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	review, ok := m.userReview(movieID, userID)
+	if !ok {
+		return Review{}, ErrNotFound
+	}
+	return m.reviewOf(review.ID), nil
+}
+
+func (m *Memory) DeleteUserReview(movieID, userID int, prior RatingPrior) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	review, ok := m.userReview(movieID, userID)
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.reviews, review.ID)
+	m.recalculateRating(movieID, prior)
+
+	return nil
+}
+
+func (m *Memory) DeleteReview(reviewID int, prior RatingPrior) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	review, ok := m.reviews[reviewID]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.reviews, reviewID)
+	m.recalculateRating(review.MovieID, prior)
+
+	return nil
+}
+
+func (m *Memory) SetReviewStatus(reviewID int, status string, prior RatingPrior) (Review, error) {
+	if status != ReviewPublished && status != ReviewHidden {
+		return Review{}, fmt.Errorf("%w: %q", ErrUnknownReviewStatus, status)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	review, ok := m.reviews[reviewID]
+	if !ok {
+		return Review{}, ErrNotFound
+	}
+	review.Status = status
+	m.reviews[reviewID] = review
+	m.recalculateRating(review.MovieID, prior)
+
+	return m.reviewOf(reviewID), nil
+}
+
+func (m *Memory) GetMovieReviews(movieID int, includeHidden bool, page Page) ([]Review, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, PageInfo{}, ErrNotFound
+	}
+
+	var reviews []Review
+	ids := sortedKeys(m.reviews)
+	slices.Reverse(ids)
+	for _, id := range ids {
+		review := m.reviews[id]
+		if review.MovieID == movieID && (includeHidden || review.Status == ReviewPublished) {
+			reviews = append(reviews, m.reviewOf(id))
+		}
+	}
+
+	return paginate(reviews, sortByNewest, page, reviewKey)
+}
+
+func (m *Memory) GetMovieRatingStats(movieID int) (RatingStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok {
+		return RatingStats{}, ErrNotFound
+	}
+	stats := m.votes[movieID]
+	stats.Rating = movie.Rating
+	return stats, nil
+}
+
+func (m *Memory) GetWatchlist(userID int, page Page) ([]WatchlistItem, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []WatchlistItem
+	for movieID, addedAt := range m.watchlist[userID] {
+		items = append(items, WatchlistItem{Movie: m.movies[movieID], AddedAt: addedAt})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		iValue, iID := watchlistKey(items[i])
+		jValue, jID := watchlistKey(items[j])
+		return compareKeys(sortByAddedAt, iValue, iID, jValue, jID) < 0
+	})
+
+	return paginate(items, sortByAddedAt, page, watchlistKey)
+}
+
+func (m *Memory) AddToWatchlist(userID, movieID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkUserMovie(userID, movieID); err != nil {
+		return false, err
+	}
+
+	watchlist, ok := m.watchlist[userID]
+	if !ok {
+		watchlist = make(map[int]time.Time)
+		m.watchlist[userID] = watchlist
+	}
+	if _, ok := watchlist[movieID]; ok {
+		return false, nil
+	}
+	watchlist[movieID] = timestamp()
+
+	return true, nil
+}
+
+func (m *Memory) RemoveFromWatchlist(userID, movieID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.watchlist[userID][movieID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.watchlist[userID], movieID)
+
+	return nil
+}
+
+func (m *Memory) GetWatched(userID int, page Page) ([]WatchedEntry, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []WatchedEntry
+	for _, id := range sortedKeys(m.watched) {
+		if entry := m.watched[id]; entry.UserID == userID {
+			entries = append(entries, m.watchedOf(id))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		iValue, iID := watchedKey(entries[i])
+		jValue, jID := watchedKey(entries[j])
+		return compareKeys(sortByWatchedOn, iValue, iID, jValue, jID) < 0
+	})
+
+	return paginate(entries, sortByWatchedOn, page, watchedKey)
+}
+
+func (m *Memory) AddWatched(entry WatchedEntry) (WatchedEntry, error) {
+	if err := checkWatched(entry); err != nil {
+		return WatchedEntry{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkUserMovie(entry.UserID, entry.Movie.ID); err != nil {
+		return WatchedEntry{}, err
+	}
+
+	entry.ID = m.nextWatchedID
+	entry.WatchedOn = truncateDate(entry.WatchedOn)
+	if entry.WatchedOn.IsZero() {
+		entry.WatchedOn = truncateDate(time.Now())
+	}
+	m.watched[entry.ID] = entry
+	m.nextWatchedID++
+
+	return m.watchedOf(entry.ID), nil
+}
+
+func (m *Memory) DeleteWatched(userID, entryID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.watched[entryID]; !ok || entry.UserID != userID {
+		return ErrNotFound
+	}
+	delete(m.watched, entryID)
+
+	return nil
+}
+
+func (m *Memory) GetLists(userID int) ([]MovieList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	lists := []MovieList{}
+	for _, id := range sortedKeys(m.lists) {
+		if list := m.lists[id]; list.UserID == userID {
+			list.MovieCount = len(m.listItems[id])
+			lists = append(lists, list)
+		}
+	}
+
+	return lists, nil
+}
+
+func (m *Memory) AddList(list MovieList) (MovieListDetail, error) {
+	if err := checkListColumns(list); err != nil {
+		return MovieListDetail{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[list.UserID]; !ok {
+		return MovieListDetail{}, fmt.Errorf("%w: user %d does not exist", ErrForeignKeyViolation, list.UserID)
+	}
+	if err := m.checkListSlug(list); err != nil {
+		return MovieListDetail{}, err
+	}
+
+	list.ID = m.nextListID
+	list.CreatedAt = timestamp()
+	m.lists[list.ID] = list
+	m.nextListID++
+
+	return m.listDetail(list.ID), nil
+}
+
+func (m *Memory) GetList(userID, listID int) (MovieListDetail, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if list, ok := m.lists[listID]; !ok || list.UserID != userID {
+		return MovieListDetail{}, ErrNotFound
+	}
+
+	return m.listDetail(listID), nil
+}
+
+func (m *Memory) UpdateList(list MovieList) (MovieListDetail, error) {
+	if err := checkListColumns(list); err != nil {
+		return MovieListDetail{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.lists[list.ID]
+	if !ok || stored.UserID != list.UserID {
+		return MovieListDetail{}, ErrNotFound
+	}
+	if err := m.checkListSlug(list); err != nil {
+		return MovieListDetail{}, err
+	}
+
+	stored.Name, stored.Description, stored.Slug, stored.Public = list.Name, list.Description, list.Slug, list.Public
+	m.lists[list.ID] = stored
+
+	return m.listDetail(list.ID), nil
+}
+
+func (m *Memory) DeleteList(userID, listID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if list, ok := m.lists[listID]; !ok || list.UserID != userID {
+		return ErrNotFound
+	}
+	delete(m.lists, listID)
+	delete(m.listItems, listID)
+
+	return nil
+}
+
+func (m *Memory) AddListMovie(userID, listID, movieID int) (MovieListDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if list, ok := m.lists[listID]; !ok || list.UserID != userID {
+		return MovieListDetail{}, ErrNotFound
+	}
+	if _, ok := m.movies[movieID]; !ok {
+		return MovieListDetail{}, fmt.Errorf("%w: movie %d does not exist", ErrForeignKeyViolation, movieID)
+	}
+
+	items, ok := m.listItems[listID]
+	if !ok {
+		items = make(map[int]time.Time)
+		m.listItems[listID] = items
+	}
+	if _, ok := items[movieID]; !ok {
+		items[movieID] = timestamp()
+	}
+
+	return m.listDetail(listID), nil
+}
+
+func (m *Memory) RemoveListMovie(userID, listID, movieID int) (MovieListDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if list, ok := m.lists[listID]; !ok || list.UserID != userID {
+		return MovieListDetail{}, ErrNotFound
+	}
+	if _, ok := m.listItems[listID][movieID]; !ok {
+		return MovieListDetail{}, ErrNotFound
+	}
+	delete(m.listItems[listID], movieID)
+
+	return m.listDetail(listID), nil
+}
+
+func (m *Memory) GetPublicList(slug string) (MovieListDetail, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for id, list := range m.lists {
+		if list.Slug == slug && list.Public {
+			return m.listDetail(id), nil
+		}
+	}
+
+	return MovieListDetail{}, ErrNotFound
+}
+
+// RebuildSimilarities считает похожие фильмы по снимку данных, не удерживая
+// блокировку на время расчёта.
+func (m *Memory) RebuildSimilarities(weights SimilarityWeights) (int, error) {
+	m.mu.RLock()
+	in := m.similarityInput()
+	m.mu.RUnlock()
+
+	similarities := make(map[int][]movieSimilarity)
+	saved := 0
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range computeSimilarities(in, weights) {
+		_, movieOK := m.movies[s.movieID]
+		_, similarOK := m.movies[s.similarID]
+		if movieOK && similarOK {
+			similarities[s.movieID] = append(similarities[s.movieID], s)
+			saved++
+		}
+	}
+	m.similarities = similarities
+
+	return saved, nil
+}
+
+func (m *Memory) GetSimilarMovies(movieID, limit int) ([]SimilarMovie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.movies[movieID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	similar := []SimilarMovie{}
+	for _, s := range m.similarities[movieID] {
+		if len(similar) == limit {
+			break
+		}
+		similar = append(similar, SimilarMovie{
+			Movie:        m.movies[s.similarID],
+			Score:        s.score,
+			SharedActors: s.sharedActors,
+			SharedGenres: s.sharedGenres,
+		})
+	}
+
+	return similar, nil
+}
+
+func (m *Memory) GetRecommendations(userID, limit int) ([]Recommendation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seeds := m.recommendationSeeds(userID)
+	scores := make(map[int]float64)
+	for movieID, weight := range seeds {
+		for _, s := range m.similarities[movieID] {
+			if _, ok := seeds[s.similarID]; !ok {
+				scores[s.similarID] += weight * s.score
+			}
+		}
+	}
+
+	recommendations := []Recommendation{}
+	for _, movieID := range sortedKeys(scores) {
+		if scores[movieID] > 0 {
+			recommendations = append(recommendations, Recommendation{Movie: m.movies[movieID], Score: scores[movieID]})
+		}
+	}
+	slices.SortStableFunc(recommendations, func(a, b Recommendation) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations, nil
+}
+
+func (m *Memory) GetMoviesByPeriod(filter MovieFilter, years int) ([]PeriodStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	periods := make(map[int]PeriodStats)
+	ratings := make(map[int]*ratingAverage)
+	for _, movieID := range m.filteredMovieIDs(filter) {
+		movie := m.movies[movieID]
+		if movie.ReleaseDate.IsZero() {
+			continue
+		}
+		year := movie.ReleaseDate.Year() / years * years
+		period := periods[year]
+		period.Movies++
+		periods[year] = period
+		if ratings[year] == nil {
+			ratings[year] = &ratingAverage{}
+		}
+		ratings[year].add(movie.Rating)
+	}
+
+	stats := []PeriodStats{}
+	for _, year := range sortedKeys(periods) {
+		period := periods[year]
+		period.Period = year
+		period.AverageRating = ratings[year].value()
+		stats = append(stats, period)
+	}
+
+	return stats, nil
+}
+
+func (m *Memory) GetGenreStats(filter MovieFilter) ([]GenreStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[int]GenreStats, len(m.genres))
+	ratings := make(map[int]*ratingAverage, len(m.genres))
+	for genreID, genre := range m.genres {
+		stats[genreID] = GenreStats{Genre: genre}
+		ratings[genreID] = &ratingAverage{}
+	}
+	for _, movieID := range m.filteredMovieIDs(filter) {
+		for genreID := range m.movieGenres[movieID] {
+			genre := stats[genreID]
+			genre.Movies++
+			stats[genreID] = genre
+			ratings[genreID].add(m.movies[movieID].Rating)
+		}
+	}
+
+	genres := []GenreStats{}
+	for _, genreID := range sortedKeys(stats) {
+		genre := stats[genreID]
+		genre.AverageRating = ratings[genreID].value()
+		genres = append(genres, genre)
+	}
+	slices.SortStableFunc(genres, func(a, b GenreStats) int {
+		return cmp.Or(cmp.Compare(b.Movies, a.Movies), cmp.Compare(a.Name, b.Name))
+	})
+
+	return genres, nil
+}
+
+func (m *Memory) GetProlificActors(filter MovieFilter, limit int) ([]ActorStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for _, movieID := range m.filteredMovieIDs(filter) {
+		for actorID := range m.movieActors[movieID] {
+			counts[actorID]++
+		}
+	}
+
+	actors := []ActorStats{}
+	for _, actorID := range sortedKeys(counts) {
+		actors = append(actors, ActorStats{Actor: m.actors[actorID], Movies: counts[actorID]})
+	}
+	slices.SortStableFunc(actors, func(a, b ActorStats) int {
+		return cmp.Or(cmp.Compare(b.Movies, a.Movies), cmp.Compare(a.Name, b.Name))
+	})
+	if len(actors) > limit {
+		actors = actors[:limit]
+	}
+
+	return actors, nil
+}
+
+func (m *Memory) GetActorAgeStats(filter MovieFilter) (AgeStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ages := make(map[int]int)
+	for _, movieID := range m.filteredMovieIDs(filter) {
+		releaseDate := m.movies[movieID].ReleaseDate
+		if releaseDate.IsZero() {
+			continue
+		}
+		for actorID := range m.movieActors[movieID] {
+			birthdate := m.actors[actorID].Birthdate
+			if birthdate == nil || birthdate.After(releaseDate) {
+				continue
+			}
+			ages[fullYears(*birthdate, releaseDate)]++
+		}
+	}
+
+	return ageStats(ages), nil
+}
+
+func (m *Memory) GetRatingHistogram(filter MovieFilter, source string) ([]HistogramBucket, error) {
+	if source != RatingSourceMovies && source != RatingSourceReviews {
+		return nil, ErrUnknownRatingSource
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	histogram := ratingHistogram()
+	count := func(rating float64) {
+		if i := ratingBucket(rating); i >= 0 {
+			histogram[i].Count++
+		}
+	}
+
+	movieIDs := m.filteredMovieIDs(filter)
+	if source == RatingSourceMovies {
+		for _, movieID := range movieIDs {
+			if rating := m.movies[movieID].Rating; rating != nil {
+				count(*rating)
+			}
+		}
+		return histogram, nil
+	}
+
+	matched := make(map[int]struct{}, len(movieIDs))
+	for _, movieID := range movieIDs {
+		matched[movieID] = struct{}{}
+	}
+	for _, review := range m.reviews {
+		if _, ok := matched[review.MovieID]; ok && review.Status == ReviewPublished {
+			count(float64(review.Score))
+		}
+	}
+
+	return histogram, nil
+}
+
+func (m *Memory) GetCoStars(actorID, limit int) ([]CoStar, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.actors[actorID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	shared := make(map[int]int)
+	for _, cast := range m.movieActors {
+		if _, ok := cast[actorID]; !ok {
+			continue
+		}
+		for coStarID := range cast {
+			if coStarID != actorID {
+				shared[coStarID]++
+			}
+		}
+	}
+
+	coStars := []CoStar{}
+	for _, coStarID := range sortedKeys(shared) {
+		coStars = append(coStars, CoStar{Actor: m.actors[coStarID], SharedMovies: shared[coStarID]})
+	}
+	slices.SortStableFunc(coStars, func(a, b CoStar) int {
+		return cmp.Or(cmp.Compare(b.SharedMovies, a.SharedMovies), cmp.Compare(a.Name, b.Name))
+	})
+	if len(coStars) > limit {
+		coStars = coStars[:limit]
+	}
+
+	return coStars, nil
+}
+
+func (m *Memory) GetActorPath(fromID, toID, maxDepth int) (ActorPath, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, actorID := range []int{fromID, toID} {
+		if _, ok := m.actors[actorID]; !ok {
+			return ActorPath{}, ErrNotFound
+		}
+	}
+
+	actorMovies := make(map[int][]int)
+	for _, movieID := range sortedKeys(m.movieActors) {
+		for actorID := range m.movieActors[movieID] {
+			actorMovies[actorID] = append(actorMovies[actorID], movieID)
+		}
+	}
+
+	actorIDs, movieIDs, err := shortestPath(fromID, toID, maxDepth, func(frontier []int, visited map[int]graphEdge) (map[int]graphEdge, error) {
+		depth := visited[frontier[0]].depth + 1
+		next := make(map[int]graphEdge)
+		for _, actorID := range frontier {
+			for _, movieID := range actorMovies[actorID] {
+				for coStarID := range m.movieActors[movieID] {
+					if _, ok := visited[coStarID]; ok {
+						continue
+					}
+					// Как DISTINCT ON в Postgres: из нескольких рёбер берётся
+					// ребро с наименьшими id актёра и фильма.
+					edge, ok := next[coStarID]
+					if !ok || actorID < edge.from || (actorID == edge.from && movieID < edge.movieID) {
+						next[coStarID] = graphEdge{from: actorID, movieID: movieID, depth: depth}
+					}
+				}
+			}
+		}
+		return next, nil
+	})
+	if err != nil {
+		return ActorPath{}, err
+	}
+
+	return buildActorPath(actorIDs, movieIDs, m.actorOf, m.movieOf)
+}
+
+func (m *Memory) SearchActors(text string, page Page) ([]ActorSearchHit, PageInfo, error) {
+	terms := searchTerms(text)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hits []ActorSearchHit
+	for _, id := range sortedKeys(m.actors) {
+		actor := m.actors[id]
+		if containsAll(actor.Name, terms) {
+			hits = append(hits, ActorSearchHit{Actor: actor, Rank: 0.1, Highlight: highlight(actor.Name, terms)})
+		}
+	}
+
+	sortHits(hits, actorHitKey)
+	return paginate(hits, sortByRank, page, actorHitKey)
+}
+
+func (m *Memory) SearchMoviesFuzzy(titleFragment, actorName string, threshold float64, page Page) ([]Movie, PageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []Movie
+	for _, id := range sortedKeys(m.movies) {
+		movie := m.movies[id]
+		if titleFragment != "" && wordSimilarity(titleFragment, movie.Title) >= threshold {
+			movies = append(movies, movie)
+			continue
+		}
+		if actorName == "" {
+			continue
+		}
+		for actorID := range m.movieActors[id] {
+			if wordSimilarity(actorName, m.actors[actorID].Name) >= threshold {
+				movies = append(movies, movie)
+				break
+			}
+		}
+	}
+
+	return paginate(movies, sortByID, page, movieKey(sortByID.Column))
+}
+
+func (m *Memory) SuggestActors(text string, threshold float64, limit int) ([]Suggestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var suggestions []Suggestion
+	for _, id := range sortedKeys(m.actors) {
+		if suggestion, ok := suggestFor(id, m.actors[id].Name, text, threshold); ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	return topSuggestions(suggestions, limit), nil
+}
+
+func (m *Memory) SuggestMovies(text string, threshold float64, limit int) ([]Suggestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var suggestions []Suggestion
+	for _, id := range sortedKeys(m.movies) {
+		if suggestion, ok := suggestFor(id, m.movies[id].Title, text, threshold); ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	return topSuggestions(suggestions, limit), nil
+}
+
+func (m *Memory) AddUser(user User) error {
+	if utf8.RuneCountInString(user.Username) > maxUsernameLength {
+		return fmt.Errorf("%w: users.username", ErrValueTooLong)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.users {
+		if stored.Username == user.Username {
+			return fmt.Errorf("%w: (username)=(%s)", ErrDuplicateKey, user.Username)
+		}
+	}
+
+	user.Id = m.nextUserID
+	user.CreatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	m.users[user.Id] = user
+	m.nextUserID++
+
+	return nil
+}
+
+func (m *Memory) GetUserByUsername(username string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return User{}, ErrNotFound
+}
+
+func (m *Memory) GetUserByToken(tokenHash string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	token, ok := m.tokens[tokenHash]
+	if !ok || token.RevokedAt != nil || !token.ExpiresAt.After(time.Now()) {
+		return User{}, ErrNotFound
+	}
+
+	user, ok := m.users[token.UserId]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (m *Memory) SetUserRole(userID int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Role = role
+	m.users[userID] = user
+
+	return nil
+}
+
+func (m *Memory) AddToken(token Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[token.UserId]; !ok {
+		return fmt.Errorf("%w: user %d does not exist", ErrForeignKeyViolation, token.UserId)
+	}
+	if _, ok := m.tokens[token.Hash]; ok {
+		return fmt.Errorf("%w: token_hash", ErrDuplicateKey)
+	}
+
+	token.Id = m.nextTokenID
+	token.CreatedAt = time.Now()
+	m.tokens[token.Hash] = token
+	m.nextTokenID++
+
+	return nil
+}
+
+func (m *Memory) RevokeToken(tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if token, ok := m.tokens[tokenHash]; ok && token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		m.tokens[tokenHash] = token
+	}
+
+	return nil
+}
+
+func (m *Memory) RevokeUserTokens(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for hash, token := range m.tokens {
+		if token.UserId == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			m.tokens[hash] = token
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) creditOf(movieID, actorID int) MovieCredit {
+	member := m.movieActors[movieID][actorID]
+	return MovieCredit{
+		Movie:      m.movies[movieID],
+		Character:  member.Character,
+		Billing:    member.Billing,
+		CreditType: member.CreditType,
+	}
+}
+
+// castOf возвращает состав фильма в том же порядке, что и castQuery.
+func (m *Memory) castOf(movieID int) []CastMember {
+	cast := []CastMember{}
+	for _, actorID := range sortedKeys(m.movieActors[movieID]) {
+		member := m.movieActors[movieID][actorID]
+		member.Name = m.actors[actorID].Name
+		cast = append(cast, member)
+	}
+
+	sort.SliceStable(cast, func(i, j int) bool {
+		bi, bj := cast[i].Billing, cast[j].Billing
+		if bi == 0 || bj == 0 {
+			return bi != 0 && bj == 0
+		}
+		return bi < bj
+	})
+	return cast
+}
+
+// checkGenreName повторяет уникальный индекс genres_name_key по lower(name).
+func (m *Memory) checkGenreName(genre Genre) error {
+	for id, stored := range m.genres {
+		if id != genre.ID && strings.ToLower(stored.Name) == strings.ToLower(genre.Name) {
+			return fmt.Errorf("%w: genres.name=%q", ErrDuplicateKey, genre.Name)
+		}
+	}
+	return nil
+}
+
+// genresOf возвращает жанры фильма в том же порядке, что и movieGenresQuery.
+func (m *Memory) genresOf(movieID int) []Genre {
+	genres := []Genre{}
+	for _, genreID := range sortedKeys(m.movieGenres[movieID]) {
+		genres = append(genres, m.genres[genreID])
+	}
+	sortGenres(genres)
+	return genres
+}
+
+// genreFacets повторяет queryGenreFacets для фильмов movieIDs.
+func (m *Memory) genreFacets(movieIDs []int) []GenreFacet {
+	counts := make(map[int]int)
+	for _, movieID := range movieIDs {
+		for genreID := range m.movieGenres[movieID] {
+			counts[genreID]++
+		}
+	}
+
+	facets := []GenreFacet{}
+	for _, genreID := range sortedKeys(counts) {
+		facets = append(facets, GenreFacet{Genre: m.genres[genreID], Count: counts[genreID]})
+	}
+	sort.SliceStable(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Name < facets[j].Name
+	})
+	return facets
+}
+
+func sortGenres(genres []Genre) {
+	sort.SliceStable(genres, func(i, j int) bool {
+		return genres[i].Name < genres[j].Name
+	})
+}
+
+func (m *Memory) userReview(movieID, userID int) (Review, bool) {
+	for _, review := range m.reviews {
+		if review.MovieID == movieID && review.UserID == userID {
+			return review, true
+		}
+	}
+	return Review{}, false
+}
+
+func (m *Memory) reviewOf(reviewID int) Review {
+	review := m.reviews[reviewID]
+	review.Username = m.users[review.UserID].Username
+	return review
+}
+
+// recalculateRating повторяет одноимённую функцию для Postgres.
+func (m *Memory) recalculateRating(movieID int, prior RatingPrior) {
+	var stats RatingStats
+	sum := 0
+	for _, review := range m.reviews {
+		if review.MovieID == movieID && review.Status == ReviewPublished {
+			stats.Votes++
+			sum += review.Score
+		}
+	}
+	if stats.Votes > 0 {
+		stats.Mean = float64(sum) / float64(stats.Votes)
+	}
+	m.votes[movieID] = stats
+
+	movie := m.movies[movieID]
+	movie.Rating = prior.rating(stats.Votes, stats.Mean)
+	m.movies[movieID] = movie
+}
+
+// ratingAverage повторяет avg(rating) в SQL: фильмы без рейтинга не
+// учитываются, а без единого рейтинга среднее равно 0, как COALESCE(avg, 0).
+type ratingAverage struct {
+	sum   float64
+	count int
+}
+
+func (a *ratingAverage) add(rating *float64) {
+	if rating != nil {
+		a.sum += *rating
+		a.count++
+	}
+}
+
+func (a *ratingAverage) value() float64 {
+	if a.count == 0 {
+		return 0
+	}
+	return a.sum / float64(a.count)
+}
+
+// checkUserMovie повторяет внешние ключи user_id и movie_id таблиц списков.
+func (m *Memory) checkUserMovie(userID, movieID int) error {
+	if _, ok := m.users[userID]; !ok {
+		return fmt.Errorf("%w: user %d does not exist", ErrForeignKeyViolation, userID)
+	}
+	if _, ok := m.movies[movieID]; !ok {
+		return fmt.Errorf("%w: movie %d does not exist", ErrForeignKeyViolation, movieID)
+	}
+	return nil
+}
+
+func (m *Memory) watchedOf(entryID int) WatchedEntry {
+	entry := m.watched[entryID]
+	entry.Movie = m.movies[entry.Movie.ID]
+	return entry
+}
+
+// checkListSlug повторяет уникальный индекс movie_lists.slug.
+func (m *Memory) checkListSlug(list MovieList) error {
+	for id, stored := range m.lists {
+		if id != list.ID && stored.Slug == list.Slug {
+			return fmt.Errorf("%w: slug=%s", ErrDuplicateKey, list.Slug)
+		}
+	}
+	return nil
+}
+
+// listDetail возвращает список с фильмами в порядке добавления, как queryListDetail.
+func (m *Memory) listDetail(listID int) MovieListDetail {
+	items := m.listItems[listID]
+	movieIDs := sortedKeys(items)
+	sort.SliceStable(movieIDs, func(i, j int) bool {
+		return items[movieIDs[i]].Before(items[movieIDs[j]])
+	})
+
+	detail := MovieListDetail{MovieList: m.lists[listID], Movies: []Movie{}}
+	detail.MovieCount = len(items)
+	for _, movieID := range movieIDs {
+		detail.Movies = append(detail.Movies, m.movies[movieID])
+	}
+	return detail
+}
+
+func (m *Memory) similarityInput() similarityInput {
+	in := similarityInput{
+		years:  make(map[int]int, len(m.movies)),
+		cast:   make(map[int][]int),
+		genres: make(map[int][]int),
+		scores: make(map[int]map[int]int),
+	}
+	for movieID, movie := range m.movies {
+		year := 0
+		if !movie.ReleaseDate.IsZero() {
+			year = movie.ReleaseDate.Year()
+		}
+		in.years[movieID] = year
+	}
+	for movieID, cast := range m.movieActors {
+		in.cast[movieID] = sortedKeys(cast)
+	}
+	for movieID, genres := range m.movieGenres {
+		in.genres[movieID] = sortedKeys(genres)
+	}
+	for _, review := range m.reviews {
+		if review.Status != ReviewPublished {
+			continue
+		}
+		if in.scores[review.UserID] == nil {
+			in.scores[review.UserID] = make(map[int]int)
+		}
+		in.scores[review.UserID][review.MovieID] = review.Score
+	}
+	return in
+}
+
+// recommendationSeeds повторяет seeds из запроса GetRecommendations для
+// Postgres: отзыв важнее оценки в журнале, а она - неявных сигналов.
+func (m *Memory) recommendationSeeds(userID int) map[int]float64 {
+	type signal struct {
+		weight   float64
+		priority int
+	}
+	signals := make(map[int]signal)
+	add := func(movieID int, weight float64, priority int) {
+		current, ok := signals[movieID]
+		if !ok || priority < current.priority || (priority == current.priority && weight > current.weight) {
+			signals[movieID] = signal{weight, priority}
+		}
+	}
+
+	for _, review := range m.reviews {
+		if review.UserID == userID {
+			add(review.MovieID, float64(review.Score-5)/5, 1)
+		}
+	}
+	for _, entry := range m.watched {
+		if entry.UserID != userID {
+			continue
+		}
+		if entry.Score != nil {
+			add(entry.Movie.ID, float64(*entry.Score-5)/5, 2)
+		} else {
+			add(entry.Movie.ID, watchedSignal, 3)
+		}
+	}
+	for movieID := range m.watchlist[userID] {
+		add(movieID, watchlistSignal, 3)
+	}
+
+	seeds := make(map[int]float64, len(signals))
+	for movieID, s := range signals {
+		seeds[movieID] = s.weight
+	}
+	return seeds
+}
+
+// filteredMovieIDs возвращает id фильмов, подходящих под filter, по возрастанию.
+func (m *Memory) filteredMovieIDs(filter MovieFilter) []int {
+	var movieIDs []int
+	for _, movieID := range sortedKeys(m.movies) {
+		if filter.matches(m.movies[movieID], m.movieActors[movieID], m.movieGenres[movieID]) {
+			movieIDs = append(movieIDs, movieID)
+		}
+	}
+	return movieIDs
+}
+
+// actorOf и movieOf читают записи под уже взятой блокировкой.
+func (m *Memory) actorOf(actorID int) (Actor, error) {
+	actor, ok := m.actors[actorID]
+	if !ok {
+		return Actor{}, ErrNotFound
+	}
+	return actor, nil
+}
+
+func (m *Memory) movieOf(movieID int) (Movie, error) {
+	movie, ok := m.movies[movieID]
+	if !ok {
+		return Movie{}, ErrNotFound
+	}
+	return movie, nil
+}
+
+// crewOf возвращает съёмочную группу фильма в том же порядке, что и crewQuery.
+func (m *Memory) crewOf(movieID int) []CrewMember {
+	crew := []CrewMember{}
+	for _, member := range m.movieCrew[movieID] {
+		member.Name = m.actors[member.PersonID].Name
+		crew = append(crew, member)
+	}
+
+	slices.SortStableFunc(crew, func(a, b CrewMember) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(jobs, a.Job), slices.Index(jobs, b.Job)),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.PersonID, b.PersonID),
+		)
+	})
+	return crew
+}
+
+func (m *Memory) hasActorLike(movieID int, fragment string) bool {
+	for actorID := range m.movieActors[movieID] {
+		if strings.Contains(m.actors[actorID].Name, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkActorColumns(actor Actor) error {
+	if utf8.RuneCountInString(actor.Name) > MaxActorNameLength {
+		return fmt.Errorf("%w: actors.name", ErrValueTooLong)
+	}
+	if utf8.RuneCountInString(actor.Gender) > MaxActorGenderLength {
+		return fmt.Errorf("%w: actors.gender", ErrValueTooLong)
+	}
+	return nil
+}
+
+func checkMovieColumns(movie Movie) error {
+	if utf8.RuneCountInString(movie.Title) > MaxMovieTitleLength {
+		return fmt.Errorf("%w: movies.title", ErrValueTooLong)
+	}
+	return nil
+}
+
+func checkGenreColumns(genre Genre) error {
+	if utf8.RuneCountInString(genre.Name) > maxGenreNameLength {
+		return fmt.Errorf("%w: genres.name", ErrValueTooLong)
+	}
+	return nil
+}
+
+func checkListColumns(list MovieList) error {
+	if utf8.RuneCountInString(list.Name) > maxListNameLength {
+		return fmt.Errorf("%w: movie_lists.name", ErrValueTooLong)
+	}
+	if utf8.RuneCountInString(list.Slug) > maxListSlugLength {
+		return fmt.Errorf("%w: movie_lists.slug", ErrValueTooLong)
+	}
+	return nil
+}
+
+// timestamp возвращает текущее время с точностью колонки TIMESTAMPTZ.
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// apply повторяет assign для хранилища в памяти: NULL в необязательных полях
+// хранится как нулевое значение, которое Postgres-хранилище читает из NULL.
+func apply[T any](dest *T, field Optional[T]) {
+	switch {
+	case field.Null:
+		var zero T
+		*dest = zero
+	case field.Set:
+		*dest = field.Value
+	}
+}
+
+// truncateBirthdate отбрасывает время у даты рождения, не меняя дату
+// вызывающего.
+func truncateBirthdate(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	date := truncateDate(*t)
+	return &date
+}
+
+// truncateDate отбрасывает время, как это делает колонка типа DATE.
+func truncateDate(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// paginate повторяет для упорядоченного среза то, что paginateQuery делает в SQL.
+func paginate[T any](items []T, order Sort, page Page, key func(T) (string, int)) ([]T, PageInfo, error) {
+	if err := page.check(order); err != nil {
+		return nil, PageInfo{}, err
+	}
+	total := len(items)
+
+	backward := page.Cursor != nil && page.Cursor.Backward
+	var rows []T
+	for _, item := range items {
+		if page.Cursor != nil {
+			value, id := key(item)
+			c := compareKeys(order, value, id, page.Cursor.Value, page.Cursor.ID)
+			if (!backward && c <= 0) || (backward && c >= 0) {
+				continue
+			}
+		}
+		rows = append(rows, item)
+	}
+
+	if backward {
+		slices.Reverse(rows)
+	}
+	rows = rows[min(page.Offset, len(rows)):]
+	rows = rows[:min(page.Limit+1, len(rows))]
+	reverse(rows, page)
+
+	rows, info := pageInfo(rows, page, order, total, key)
+	return rows, info, nil
+}
+
+// compareKeys сравнивает две записи по колонке сортировки и id в порядке выдачи.
+func compareKeys(order Sort, aValue string, aID int, bValue string, bID int) int {
+	// NULL идёт после значений при любом направлении, как NULLS LAST в
+	// paginateQuery.
+	if order.Nullable && (aValue == "") != (bValue == "") {
+		if aValue == "" {
+			return 1
+		}
+		return -1
+	}
+
+	c := 0
+	switch order.Column {
+	case "id":
+	case "rating", "rank":
+		a, _ := strconv.ParseFloat(aValue, 64)
+		b, _ := strconv.ParseFloat(bValue, 64)
+		c = cmp.Compare(a, b)
+	default:
+		c = cmp.Compare(aValue, bValue)
+	}
+	if c == 0 {
+		c = cmp.Compare(aID, bID)
+	}
+	if order.Desc {
+		return -c
+	}
+	return c
+}
+
+func sortHits[T any](hits []T, key func(T) (string, int)) {
+	sort.Slice(hits, func(i, j int) bool {
+		iValue, iID := key(hits[i])
+		jValue, jID := key(hits[j])
+		return compareKeys(sortByRank, iValue, iID, jValue, jID) < 0
+	})
+}
+
+func searchTerms(text string) []string {
+	var terms []string
+	for _, field := range strings.Fields(text) {
+		if term := strings.Trim(field, `"'.,;:!?()`); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func containsAll(s string, terms []string) bool {
+	if len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		if !containsFold(s, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight выделяет найденные слова так же, как ts_headline.
+func highlight(s string, terms []string) string {
+	if len(terms) == 0 {
+		return s
+	}
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	return re.ReplaceAllString(s, "<b>$0</b>")
+}
+
+func suggestFor(id int, candidate, text string, threshold float64) (Suggestion, bool) {
+	if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(text)) {
+		return Suggestion{ID: id, Text: candidate, Score: 1}, true
+	}
+	if score := wordSimilarity(text, candidate); score >= threshold {
+		return Suggestion{ID: id, Text: candidate, Score: score}, true
+	}
+	return Suggestion{}, false
+}
+
+func topSuggestions(suggestions []Suggestion, limit int) []Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Text < suggestions[j].Text
+	})
+	return suggestions[:min(limit, len(suggestions))]
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,385 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ratingOf(v float64) *float64 { return &v }
+
+func TestCompareKeys(t *testing.T) {
+	byRating := Sort{Column: "rating", Nullable: true}
+	byRatingDesc := Sort{Column: "rating", Desc: true, Nullable: true}
+	byTitleDesc := Sort{Column: "title", Desc: true}
+
+	tests := []struct {
+		name   string
+		order  Sort
+		aValue string
+		aID    int
+		bValue string
+		bID    int
+		want   int
+	}{
+		{"id по возрастанию", sortByID, "", 1, "", 2, -1},
+		{"id по убыванию", Sort{Column: "id", Desc: true}, "", 1, "", 2, 1},
+		{"рейтинг сравнивается как число", byRating, "9", 1, "10", 2, -1},
+		{"равный рейтинг решает id", byRating, "7.5", 3, "7.5", 2, 1},
+		{"рейтинг по убыванию", byRatingDesc, "9", 1, "10", 2, 1},
+		{"NULL после значений", byRating, "", 1, "0", 2, 1},
+		{"NULL после значений по убыванию", byRatingDesc, "", 1, "0", 2, 1},
+		{"значение перед NULL по убыванию", byRatingDesc, "10", 3, "", 2, -1},
+		{"два NULL решает id", byRating, "", 3, "", 2, 1},
+		{"два NULL по убыванию решает id", byRatingDesc, "", 3, "", 2, -1},
+		{"строки по убыванию", byTitleDesc, "Б", 1, "А", 2, -1},
+		{"равные строки по убыванию решает id", byTitleDesc, "А", 1, "А", 2, 1},
+		{"одна и та же запись", byTitleDesc, "А", 1, "А", 1, 0},
+	}
+	for _, tt := range tests {
+		if got := compareKeys(tt.order, tt.aValue, tt.aID, tt.bValue, tt.bID); got != tt.want {
+			t.Errorf("%s: compareKeys = %d, ожидалось %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// walkPages проходит выборку страницами по limit записей вперёд по Next, а
+// затем обратно по Prev, и возвращает id каждой страницы в порядке обхода.
+func walkPages(t *testing.T, movies []Movie, order Sort, limit int) (forward, backward [][]int) {
+	t.Helper()
+
+	key := movieKey(order.Column)
+	page := Page{Limit: limit}
+	var info PageInfo
+	for i := 0; ; i++ {
+		if i > len(movies) {
+			t.Fatalf("обход вперёд не закончился за %d страниц", i)
+		}
+		rows, next, err := paginate(movies, order, page, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forward = append(forward, movieIDs(rows))
+		info = next
+		if info.Next == nil {
+			break
+		}
+		page = Page{Limit: limit, Cursor: info.Next}
+	}
+
+	for i := 0; info.Prev != nil; i++ {
+		if i > len(movies) {
+			t.Fatalf("обход назад не закончился за %d страниц", i)
+		}
+		rows, prev, err := paginate(movies, order, Page{Limit: limit, Cursor: info.Prev}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backward = append(backward, movieIDs(rows))
+		info = prev
+	}
+	return forward, backward
+}
+
+func movieIDs(movies []Movie) []int {
+	ids := []int{}
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+	return ids
+}
+
+// sortMovies упорядочивает фильмы так, как их отдаёт ORDER BY хранилища.
+func sortMovies(movies []Movie, order Sort) []Movie {
+	key := movieKey(order.Column)
+	sorted := slices.Clone(movies)
+	slices.SortFunc(sorted, func(a, b Movie) int {
+		aValue, aID := key(a)
+		bValue, bID := key(b)
+		return compareKeys(order, aValue, aID, bValue, bID)
+	})
+	return sorted
+}
+
+func TestPaginate(t *testing.T) {
+	movies := []Movie{
+		{ID: 1, Title: "Б", Rating: ratingOf(7)},
+		{ID: 2, Title: "А", Rating: ratingOf(9)},
+		{ID: 3, Title: "Б", Rating: ratingOf(7)},
+		{ID: 4, Title: "В", Rating: ratingOf(10)},
+		{ID: 5, Title: "А", Rating: ratingOf(7)},
+		{ID: 6, Title: "Г"},
+		{ID: 7, Title: "Г"},
+	}
+
+	tests := []struct {
+		name     string
+		order    Sort
+		limit    int
+		forward  [][]int
+		backward [][]int
+	}{
+		{
+			name:     "рейтинг с повторами и NULL",
+			order:    Sort{Column: "rating", Nullable: true},
+			limit:    2,
+			forward:  [][]int{{1, 3}, {5, 2}, {4, 6}, {7}},
+			backward: [][]int{{4, 6}, {5, 2}, {1, 3}},
+		},
+		{
+			name:     "рейтинг по убыванию, NULL в конце",
+			order:    Sort{Column: "rating", Desc: true, Nullable: true},
+			limit:    2,
+			forward:  [][]int{{4, 2}, {5, 3}, {1, 7}, {6}},
+			backward: [][]int{{1, 7}, {5, 3}, {4, 2}},
+		},
+		{
+			name:     "курсор на NULL",
+			order:    Sort{Column: "rating", Nullable: true},
+			limit:    5,
+			forward:  [][]int{{1, 3, 5, 2, 4}, {6, 7}},
+			backward: [][]int{{1, 3, 5, 2, 4}},
+		},
+		{
+			name:     "название по убыванию",
+			order:    Sort{Column: "title", Desc: true},
+			limit:    3,
+			forward:  [][]int{{7, 6, 4}, {3, 1, 5}, {2}},
+			backward: [][]int{{3, 1, 5}, {7, 6, 4}},
+		},
+		{
+			name:     "страница целиком",
+			order:    sortByID,
+			limit:    7,
+			forward:  [][]int{{1, 2, 3, 4, 5, 6, 7}},
+			backward: nil,
+		},
+	}
+	for _, tt := range tests {
+		forward, backward := walkPages(t, sortMovies(movies, tt.order), tt.order, tt.limit)
+		if !slices.EqualFunc(forward, tt.forward, slices.Equal) {
+			t.Errorf("%s: вперёд %v, ожидалось %v", tt.name, forward, tt.forward)
+		}
+		if !slices.EqualFunc(backward, tt.backward, slices.Equal) {
+			t.Errorf("%s: назад %v, ожидалось %v", tt.name, backward, tt.backward)
+		}
+	}
+}
+
+func TestPaginateOffsetAndCursorCheck(t *testing.T) {
+	movies := []Movie{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	rows, info, err := paginate(movies, sortByID, Page{Limit: 1, Offset: 1}, movieKey("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := movieIDs(rows); !slices.Equal(got, []int{2}) {
+		t.Errorf("смещение 1: %v, ожидалось [2]", got)
+	}
+	if info.Total != 3 || !info.HasMore || info.Prev == nil {
+		t.Errorf("смещение 1: %+v, ожидались Total 3, HasMore и Prev", info)
+	}
+
+	cursor := &Cursor{Column: "title", ID: 1}
+	if _, _, err := paginate(movies, sortByID, Page{Limit: 1, Cursor: cursor}, movieKey("id")); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("курсор другой сортировки: %v, ожидалась ErrInvalidCursor", err)
+	}
+	if _, _, err := paginate(movies, sortByID, Page{}, movieKey("id")); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("нулевой limit: %v, ожидалась ErrInvalidCursor", err)
+	}
+}
+
+func TestMovieFilterMatches(t *testing.T) {
+	released := time.Date(2001, time.May, 1, 0, 0, 0, 0, time.UTC)
+	movie := Movie{
+		ID:          1,
+		Title:       "100% любовь_",
+		Description: "История О ЛЮБВИ",
+		ReleaseDate: released,
+		Rating:      ratingOf(8),
+	}
+	cast := map[int]CastMember{7: {ActorID: 7}}
+	genres := map[int]struct{}{1: {}, 2: {}}
+
+	tests := []struct {
+		name   string
+		movie  Movie
+		filter MovieFilter
+		want   bool
+	}{
+		{"пустой фильтр", movie, MovieFilter{}, true},
+		{"префикс с процентом", movie, MovieFilter{TitlePrefix: "100%"}, true},
+		{"процент не подстановка", movie, MovieFilter{TitlePrefix: "1%любовь"}, false},
+		{"подчёркивание не подстановка", movie, MovieFilter{TitlePrefix: "10_"}, false},
+		{"префикс с учётом регистра", movie, MovieFilter{TitlePrefix: "100% Любовь"}, false},
+		{"подстрока описания без учёта регистра", movie, MovieFilter{Description: "о любви"}, true},
+		{"подстрока описания не найдена", movie, MovieFilter{Description: "о войне"}, false},
+		{"обратная косая черта буквально", Movie{Title: `C:\кино`}, MovieFilter{TitlePrefix: `C:\`}, true},
+		{"границы дат включаются", movie, MovieFilter{ReleasedFrom: released, ReleasedTo: released}, true},
+		{"дата позже границы", movie, MovieFilter{ReleasedTo: released.AddDate(0, 0, -1)}, false},
+		{"без даты нижняя граница не проходит", Movie{}, MovieFilter{ReleasedFrom: released}, false},
+		{"без даты верхняя граница не проходит", Movie{}, MovieFilter{ReleasedTo: released}, false},
+		{"границы рейтинга включаются", movie, MovieFilter{MinRating: ratingOf(8), MaxRating: ratingOf(8)}, true},
+		{"рейтинг ниже границы", movie, MovieFilter{MinRating: ratingOf(8.5)}, false},
+		{"без рейтинга нижняя граница не проходит", Movie{}, MovieFilter{MinRating: ratingOf(0)}, false},
+		{"без рейтинга верхняя граница не проходит", Movie{}, MovieFilter{MaxRating: ratingOf(10)}, false},
+		{"один из актёров", movie, MovieFilter{ActorIDs: []int{3, 7}}, true},
+		{"чужие актёры", movie, MovieFilter{ActorIDs: []int{3}}, false},
+		{"любой из жанров", movie, MovieFilter{GenreIDs: []int{2, 5}}, true},
+		{"все жанры", movie, MovieFilter{GenreIDs: []int{1, 2}, AllGenres: true}, true},
+		{"не все жанры", movie, MovieFilter{GenreIDs: []int{2, 5}, AllGenres: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.matches(tt.movie, cast, genres); got != tt.want {
+			t.Errorf("%s: matches = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"кино":     "кино",
+		"100%":     `100\%`,
+		"a_b":      `a\_b`,
+		`C:\dir`:   `C:\\dir`,
+		`\%_`:      `\\\%\_`,
+		"":         "",
+		"%%__\\\\": `\%\%\_\_\\\\`,
+	}
+	for s, want := range tests {
+		if got := escapeLike(s); got != want {
+			t.Errorf("escapeLike(%q) = %q, ожидалось %q", s, got, want)
+		}
+	}
+}
+
+// newMemoryMovie заводит в хранилище фильм с актёром в составе и съёмочной
+// группе и отзывом пользователя.
+func newMemoryMovie(t *testing.T, m *Memory) (Movie, Actor, User) {
+	t.Helper()
+
+	actor, err := m.AddActor(Actor{Name: "Актёр"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	movie, err := m.AddMovie(Movie{Title: "Фильм", ReleaseDate: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddMovieActor(movie.ID, actor.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ReplaceMovieCrew(movie.ID, []CrewMember{{PersonID: actor.Id, Job: "director"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddUser(User{Username: "зритель"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := m.GetUserByUsername("зритель")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.SaveReview(Review{MovieID: movie.ID, UserID: user.Id, Score: 8}, RatingPrior{}); err != nil {
+		t.Fatal(err)
+	}
+	return movie, actor, user
+}
+
+func TestMemoryDeleteActorCascades(t *testing.T) {
+	m := NewMemory()
+	movie, actor, _ := newMemoryMovie(t, m)
+
+	if err := m.DeleteActor(actor.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	cast, err := m.GetMovieCast(movie.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cast) != 0 {
+		t.Errorf("после удаления актёра в составе осталось %v", cast)
+	}
+	crew, err := m.GetMovieCrew(movie.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crew) != 0 {
+		t.Errorf("после удаления актёра в съёмочной группе осталось %v", crew)
+	}
+	if _, err := m.GetMovie(movie.ID); err != nil {
+		t.Errorf("удаление актёра затронуло фильм: %v", err)
+	}
+	if err := m.DeleteActor(actor.Id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("повторное удаление: %v, ожидалась ErrNotFound", err)
+	}
+}
+
+func TestMemoryDeleteMovieCascades(t *testing.T) {
+	m := NewMemory()
+	movie, actor, user := newMemoryMovie(t, m)
+
+	if err := m.DeleteMovie(movie.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	credits, _, err := m.GetMoviesByActorID(actor.Id, Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credits) != 0 {
+		t.Errorf("после удаления фильма у актёра остались роли %v", credits)
+	}
+	crew, err := m.GetPersonCrewCredits(actor.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crew) != 0 {
+		t.Errorf("после удаления фильма у актёра остались работы %v", crew)
+	}
+	if _, err := m.GetUserReview(movie.ID, user.Id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("отзыв удалённого фильма: %v, ожидалась ErrNotFound", err)
+	}
+	if _, err := m.GetActor(actor.Id); err != nil {
+		t.Errorf("удаление фильма затронуло актёра: %v", err)
+	}
+}
+
+func TestMemoryConstraintErrors(t *testing.T) {
+	m := NewMemory()
+	movie, actor, user := newMemoryMovie(t, m)
+	if _, err := m.AddGenre(Genre{Name: "Драма"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"актёр уже в составе", m.AddMovieActor(movie.ID, actor.Id), ErrDuplicateKey},
+		{"имя пользователя занято", m.AddUser(User{Username: user.Username}), ErrDuplicateKey},
+		{"жанр без учёта регистра", second(m.AddGenre(Genre{Name: "драма"})), ErrDuplicateKey},
+		{"нет актёра", m.AddMovieActor(movie.ID, actor.Id+1), ErrForeignKeyViolation},
+		{"нет фильма", m.AddMovieActor(movie.ID+1, actor.Id), ErrForeignKeyViolation},
+		{"нет человека в группе", second(m.ReplaceMovieCrew(movie.ID, []CrewMember{{PersonID: actor.Id + 1, Job: "director"}})), ErrForeignKeyViolation},
+		{"нет пользователя у отзыва", third(m.SaveReview(Review{MovieID: movie.ID, UserID: user.Id + 1, Score: 5}, RatingPrior{})), ErrForeignKeyViolation},
+		{"длинное имя актёра", second(m.AddActor(Actor{Name: strings.Repeat("я", MaxActorNameLength+1)})), ErrValueTooLong},
+		{"длинное название фильма", second(m.AddMovie(Movie{Title: strings.Repeat("я", MaxMovieTitleLength+1)})), ErrValueTooLong},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: %v, ожидалась %v", tt.name, tt.err, tt.want)
+		}
+	}
+
+	if _, err := m.AddActor(Actor{Name: strings.Repeat("я", MaxActorNameLength)}); err != nil {
+		t.Errorf("имя актёра предельной длины: %v", err)
+	}
+}
+
+func second[T any](_ T, err error) error { return err }
+
+func third[T, U any](_ T, _ U, err error) error { return err }
@@ -0,0 +1,240 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page задаёт страницу выборки: смещение и/или keyset-курсор.
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+}
+
+// Cursor указывает на запись, от которой продолжается выборка: значение колонки
+// сортировки и id как второй ключ. Backward означает движение к началу выборки.
+type Cursor struct {
+	Column   string `json:"c"`
+	Desc     bool   `json:"d,omitempty"`
+	Value    string `json:"v,omitempty"`
+	ID       int    `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// PageInfo описывает положение страницы в полной выборке.
+type PageInfo struct {
+	Total   int
+	HasMore bool
+	Next    *Cursor
+	Prev    *Cursor
+}
+
+// Sort задаёт порядок выборки. Column должна быть из белого списка хранилища.
+// Nullable отмечает колонку, в которой бывает NULL: такие записи идут в конце
+// выборки при любом направлении (NULLS LAST), а в ключе и курсоре NULL
+// передаётся пустой строкой.
+type Sort struct {
+	Column   string
+	Desc     bool
+	Nullable bool
+}
+
+var sortByID = Sort{Column: "id"}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return &cursor, nil
+}
+
+func (p Page) check(sort Sort) error {
+	if p.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidCursor)
+	}
+	if p.Cursor != nil && (p.Cursor.Column != sort.Column || p.Cursor.Desc != sort.Desc) {
+		return fmt.Errorf("%w: cursor was issued for another sort order", ErrInvalidCursor)
+	}
+	return nil
+}
+
+// pageInfo вычисляет курсоры соседних страниц. rows должны содержать до
+// Limit+1 записей в порядке выдачи клиенту; лишняя запись говорит о том, что
+// в направлении движения есть ещё данные.
+func pageInfo[T any](rows []T, page Page, sort Sort, total int, key func(T) (string, int)) ([]T, PageInfo) {
+	info := PageInfo{Total: total}
+
+	backward := page.Cursor != nil && page.Cursor.Backward
+	more := len(rows) > page.Limit
+	if more {
+		if backward {
+			rows = rows[1:]
+		} else {
+			rows = rows[:page.Limit]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, info
+	}
+
+	cursorAt := func(row T, backward bool) *Cursor {
+		value, id := key(row)
+		return &Cursor{Column: sort.Column, Desc: sort.Desc, Value: value, ID: id, Backward: backward}
+	}
+
+	if backward {
+		info.HasMore = true
+		info.Next = cursorAt(rows[len(rows)-1], false)
+		if more {
+			info.Prev = cursorAt(rows[0], true)
+		}
+	} else {
+		info.HasMore = more
+		if more {
+			info.Next = cursorAt(rows[len(rows)-1], false)
+		}
+		if page.Cursor != nil || page.Offset > 0 {
+			info.Prev = cursorAt(rows[0], true)
+		}
+	}
+
+	return rows, info
+}
+
+// paginateQuery оборачивает базовый запрос в подзапрос t и добавляет условие
+// курсора, сортировку и LIMIT/OFFSET. Имена колонок берутся только из Sort,
+// значения передаются параметрами.
+func paginateQuery(base string, args []interface{}, sort Sort, page Page) (string, []interface{}) {
+	backward := page.Cursor != nil && page.Cursor.Backward
+	desc := sort.Desc != backward
+
+	var query strings.Builder
+	query.WriteString("SELECT * FROM (" + base + ") AS t")
+
+	if page.Cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		switch {
+		case sort.Column == "id":
+			args = append(args, page.Cursor.ID)
+			query.WriteString(" WHERE t.id " + op + " $" + strconv.Itoa(len(args)))
+		case sort.Nullable && page.Cursor.Value == "":
+			// Курсор на записи с NULL: вперёд остаются только NULL с id дальше
+			// курсора, назад - все значения и NULL с id ближе к началу.
+			args = append(args, page.Cursor.ID)
+			connective := "IS NULL AND"
+			if backward {
+				connective = "IS NOT NULL OR"
+			}
+			query.WriteString(fmt.Sprintf(" WHERE (t.%s %s t.id %s $%d)", sort.Column, connective, op, len(args)))
+		default:
+			args = append(args, page.Cursor.Value, page.Cursor.ID)
+			condition := fmt.Sprintf("(t.%s, t.id) %s ($%d, $%d)", sort.Column, op, len(args)-1, len(args))
+			// Сравнение с NULL ложно, поэтому NULL в конце выборки добавляются
+			// явно; при движении назад они и не нужны.
+			if sort.Nullable && !backward {
+				condition = fmt.Sprintf("(%s OR t.%s IS NULL)", condition, sort.Column)
+			}
+			query.WriteString(" WHERE " + condition)
+		}
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	switch {
+	case sort.Column == "id":
+		query.WriteString(" ORDER BY t.id " + direction)
+	case sort.Nullable:
+		nulls := "LAST"
+		if backward {
+			nulls = "FIRST"
+		}
+		query.WriteString(fmt.Sprintf(" ORDER BY t.%s %s NULLS %s, t.id %s", sort.Column, direction, nulls, direction))
+	default:
+		query.WriteString(fmt.Sprintf(" ORDER BY t.%s %s, t.id %s", sort.Column, direction, direction))
+	}
+
+	args = append(args, page.Limit+1)
+	query.WriteString(" LIMIT $" + strconv.Itoa(len(args)))
+	if page.Offset > 0 {
+		args = append(args, page.Offset)
+		query.WriteString(" OFFSET $" + strconv.Itoa(len(args)))
+	}
+
+	return query.String(), args
+}
+
+func countQuery(base string) string {
+	return "SELECT count(*) FROM (" + base + ") AS t"
+}
+
+// reverse разворачивает строки, прочитанные при движении назад, в порядок выдачи.
+func reverse[T any](rows []T, page Page) {
+	if page.Cursor == nil || !page.Cursor.Backward {
+		return
+	}
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+}
+
+func movieSort(orderBy, sortOrder string) (Sort, error) {
+	switch orderBy {
+	case "title", "rating", "release_date":
+	default:
+		return Sort{}, fmt.Errorf("%w: %q", ErrUnknownSortColumn, orderBy)
+	}
+	// Рейтинга нет у фильма без оценок, дата выхода может быть не указана.
+	nullable := orderBy == "rating" || orderBy == "release_date"
+	return Sort{Column: orderBy, Desc: strings.EqualFold(sortOrder, "desc"), Nullable: nullable}, nil
+}
+
+// movieKey возвращает значение колонки сортировки фильма в виде, пригодном для
+// курсора; у фильма без рейтинга или даты выхода значение пустое, как NULL.
+func movieKey(column string) func(Movie) (string, int) {
+	return func(movie Movie) (string, int) {
+		switch column {
+		case "title":
+			return movie.Title, movie.ID
+		case "rating":
+			if movie.Rating == nil {
+				return "", movie.ID
+			}
+			return strconv.FormatFloat(*movie.Rating, 'g', -1, 64), movie.ID
+		case "release_date":
+			if movie.ReleaseDate.IsZero() {
+				return "", movie.ID
+			}
+			return movie.ReleaseDate.Format(time.DateOnly), movie.ID
+		}
+		return "", movie.ID
+	}
+}
+
+func actorKey(actor Actor) (string, int) {
+	return "", actor.Id
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPaginateQueryNullable(t *testing.T) {
+	byRating := Sort{Column: "rating", Nullable: true}
+	byRatingDesc := Sort{Column: "rating", Desc: true, Nullable: true}
+
+	tests := []struct {
+		name  string
+		sort  Sort
+		page  Page
+		where string
+		order string
+		args  []interface{}
+	}{
+		{
+			name:  "первая страница",
+			sort:  byRating,
+			page:  Page{Limit: 2},
+			order: "ORDER BY t.rating ASC NULLS LAST, t.id ASC",
+			args:  []interface{}{3},
+		},
+		{
+			name:  "вперёд от значения захватывает NULL",
+			sort:  byRating,
+			page:  Page{Limit: 2, Cursor: &Cursor{Column: "rating", Value: "7.5", ID: 4}},
+			where: "WHERE ((t.rating, t.id) > ($1, $2) OR t.rating IS NULL)",
+			order: "ORDER BY t.rating ASC NULLS LAST, t.id ASC",
+			args:  []interface{}{"7.5", 4, 3},
+		},
+		{
+			name:  "назад от значения без NULL",
+			sort:  byRating,
+			page:  Page{Limit: 2, Cursor: &Cursor{Column: "rating", Value: "7.5", ID: 4, Backward: true}},
+			where: "WHERE (t.rating, t.id) < ($1, $2)",
+			order: "ORDER BY t.rating DESC NULLS FIRST, t.id DESC",
+			args:  []interface{}{"7.5", 4, 3},
+		},
+		{
+			name:  "вперёд от NULL",
+			sort:  byRating,
+			page:  Page{Limit: 2, Cursor: &Cursor{Column: "rating", ID: 4}},
+			where: "WHERE (t.rating IS NULL AND t.id > $1)",
+			order: "ORDER BY t.rating ASC NULLS LAST, t.id ASC",
+			args:  []interface{}{4, 3},
+		},
+		{
+			name:  "назад от NULL",
+			sort:  byRating,
+			page:  Page{Limit: 2, Cursor: &Cursor{Column: "rating", ID: 4, Backward: true}},
+			where: "WHERE (t.rating IS NOT NULL OR t.id < $1)",
+			order: "ORDER BY t.rating DESC NULLS FIRST, t.id DESC",
+			args:  []interface{}{4, 3},
+		},
+		{
+			name:  "по убыванию вперёд от NULL",
+			sort:  byRatingDesc,
+			page:  Page{Limit: 2, Cursor: &Cursor{Column: "rating", Desc: true, ID: 4}},
+			where: "WHERE (t.rating IS NULL AND t.id < $1)",
+			order: "ORDER BY t.rating DESC NULLS LAST, t.id DESC",
+			args:  []interface{}{4, 3},
+		},
+		{
+			name:  "колонка без NULL",
+			sort:  Sort{Column: "title"},
+			page:  Page{Limit: 2, Cursor: &Cursor{Column: "title", Value: "", ID: 4}},
+			where: "WHERE (t.title, t.id) > ($1, $2)",
+			order: "ORDER BY t.title ASC, t.id ASC",
+			args:  []interface{}{"", 4, 3},
+		},
+	}
+	for _, tt := range tests {
+		query, args := paginateQuery("SELECT * FROM movies", nil, tt.sort, tt.page)
+		if tt.where != "" && !strings.Contains(query, ") AS t "+tt.where+" ORDER BY") {
+			t.Errorf("%s: в запросе %q нет условия %q", tt.name, query, tt.where)
+		}
+		if tt.where == "" && strings.Contains(query, "WHERE") {
+			t.Errorf("%s: в запросе %q не должно быть условия", tt.name, query)
+		}
+		if !strings.Contains(query, " "+tt.order+" LIMIT") {
+			t.Errorf("%s: в запросе %q нет сортировки %q", tt.name, query, tt.order)
+		}
+		if !slices.Equal(args, tt.args) {
+			t.Errorf("%s: параметры %v, ожидались %v", tt.name, args, tt.args)
+		}
+	}
+}
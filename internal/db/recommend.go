@@ -0,0 +1,409 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"math"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// SimilarityWeights задаёт вклад признаков в похожесть фильмов и размер
+// хранимой выборки похожих фильмов.
+type SimilarityWeights struct {
+	Actors  float64
+	Genres  float64
+	Era     float64
+	Ratings float64
+	// EraYears - разница в годах выхода, при которой сходство по эпохе
+	// становится нулевым.
+	EraYears int
+	// MinCoRaters - сколько пользователей должны оценить оба фильма, чтобы
+	// учитывать сходство оценок.
+	MinCoRaters int
+	// Neighbours - сколько похожих фильмов хранить для каждого фильма.
+	Neighbours int
+}
+
+// SimilarMovie - фильм, похожий на заданный. Score лежит в диапазоне 0..1.
+type SimilarMovie struct {
+	Movie
+	Score        float64 `json:"score"`
+	SharedActors int     `json:"shared_actors"`
+	SharedGenres int     `json:"shared_genres"`
+}
+
+// Recommendation - фильм, рекомендованный пользователю.
+type Recommendation struct {
+	Movie
+	Score float64 `json:"score"`
+}
+
+// Веса сигналов пользователя для рекомендаций. Явная оценка (отзыв или
+// оценка в журнале просмотров) переводится в диапазон -1..1, так что низкие
+// оценки отталкивают от похожих фильмов.
+const (
+	watchedSignal   = 0.2
+	watchlistSignal = 0.4
+)
+
+type movieSimilarity struct {
+	movieID      int
+	similarID    int
+	score        float64
+	sharedActors int
+	sharedGenres int
+}
+
+// similarityInput - данные, по которым считается похожесть фильмов.
+type similarityInput struct {
+	years  map[int]int
+	cast   map[int][]int
+	genres map[int][]int
+	// scores - опубликованные оценки: пользователь -> фильм -> оценка.
+	scores map[int]map[int]int
+}
+
+// computeSimilarities считает для каждого фильма до Neighbours самых похожих.
+// Кандидаты - фильмы с общими актёрами, жанрами или общими оценившими
+// пользователями; сходство по эпохе только усиливает остальные признаки.
+func computeSimilarities(in similarityInput, weights SimilarityWeights) []movieSimilarity {
+	total := weights.Actors + weights.Genres + weights.Era + weights.Ratings
+	if total <= 0 {
+		return nil
+	}
+
+	actorMovies := invertLinks(in.cast)
+	genreMovies := invertLinks(in.genres)
+	ratings := centeredRatings(in.scores)
+
+	var result []movieSimilarity
+	for _, movieID := range sortedKeys(in.years) {
+		sharedActors := countShared(movieID, in.cast[movieID], actorMovies)
+		sharedGenres := countShared(movieID, in.genres[movieID], genreMovies)
+
+		candidates := make(map[int]struct{})
+		for other := range sharedActors {
+			candidates[other] = struct{}{}
+		}
+		for other := range sharedGenres {
+			candidates[other] = struct{}{}
+		}
+		for userID := range ratings[movieID] {
+			for other := range in.scores[userID] {
+				if other != movieID {
+					candidates[other] = struct{}{}
+				}
+			}
+		}
+
+		var neighbours []movieSimilarity
+		for _, other := range sortedKeys(candidates) {
+			actors := 0.0
+			if n := sharedActors[other]; n > 0 {
+				actors = float64(n) / math.Sqrt(float64(len(in.cast[movieID])*len(in.cast[other])))
+			}
+			genres := 0.0
+			if n := sharedGenres[other]; n > 0 {
+				genres = float64(n) / float64(len(in.genres[movieID])+len(in.genres[other])-n)
+			}
+			rating := adjustedCosine(ratings[movieID], ratings[other], weights.MinCoRaters)
+			if actors == 0 && genres == 0 && rating == 0 {
+				continue
+			}
+			era := eraSimilarity(in.years[movieID], in.years[other], weights.EraYears)
+
+			neighbours = append(neighbours, movieSimilarity{
+				movieID:      movieID,
+				similarID:    other,
+				score:        (weights.Actors*actors + weights.Genres*genres + weights.Era*era + weights.Ratings*rating) / total,
+				sharedActors: sharedActors[other],
+				sharedGenres: sharedGenres[other],
+			})
+		}
+
+		slices.SortStableFunc(neighbours, func(a, b movieSimilarity) int {
+			return cmp.Compare(b.score, a.score)
+		})
+		if weights.Neighbours > 0 && len(neighbours) > weights.Neighbours {
+			neighbours = neighbours[:weights.Neighbours]
+		}
+		result = append(result, neighbours...)
+	}
+
+	return result
+}
+
+// invertLinks превращает связи фильм -> [id] в id -> [фильм].
+func invertLinks(links map[int][]int) map[int][]int {
+	inverted := make(map[int][]int)
+	for _, movieID := range sortedKeys(links) {
+		for _, id := range links[movieID] {
+			inverted[id] = append(inverted[id], movieID)
+		}
+	}
+	return inverted
+}
+
+// countShared считает, сколько общих актёров или жанров у фильма с остальными.
+func countShared(movieID int, ids []int, movies map[int][]int) map[int]int {
+	shared := make(map[int]int)
+	for _, id := range ids {
+		for _, other := range movies[id] {
+			if other != movieID {
+				shared[other]++
+			}
+		}
+	}
+	return shared
+}
+
+// centeredRatings возвращает оценки фильм -> пользователь за вычетом средней
+// оценки пользователя, чтобы строгие и щедрые оценщики были сравнимы.
+func centeredRatings(scores map[int]map[int]int) map[int]map[int]float64 {
+	ratings := make(map[int]map[int]float64)
+	for userID, movies := range scores {
+		sum := 0
+		for _, score := range movies {
+			sum += score
+		}
+		mean := float64(sum) / float64(len(movies))
+
+		for movieID, score := range movies {
+			if ratings[movieID] == nil {
+				ratings[movieID] = make(map[int]float64)
+			}
+			ratings[movieID][userID] = float64(score) - mean
+		}
+	}
+	return ratings
+}
+
+// adjustedCosine - косинусная мера по пользователям, оценившим оба фильма.
+// Отрицательное сходство не делает фильмы похожими и отбрасывается.
+func adjustedCosine(a, b map[int]float64, minCoRaters int) float64 {
+	coRaters := 0
+	var dot, normA, normB float64
+	for userID, x := range a {
+		y, ok := b[userID]
+		if !ok {
+			continue
+		}
+		coRaters++
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if coRaters < max(minCoRaters, 1) || normA == 0 || normB == 0 {
+		return 0
+	}
+	return max(0, dot/math.Sqrt(normA*normB))
+}
+
+func eraSimilarity(a, b, eraYears int) float64 {
+	if a == 0 || b == 0 || eraYears <= 0 {
+		return 0
+	}
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return max(0, 1-float64(diff)/float64(eraYears))
+}
+
+// RebuildSimilarities пересчитывает таблицу movie_similarities и возвращает
+// число сохранённых пар.
+func (p *Postgres) RebuildSimilarities(weights SimilarityWeights) (int, error) {
+	in, err := p.similarityInput()
+	if err != nil {
+		return 0, err
+	}
+	similarities := computeSimilarities(in, weights)
+
+	movieIDs := make([]int64, 0, len(similarities))
+	similarIDs := make([]int64, 0, len(similarities))
+	scores := make([]float64, 0, len(similarities))
+	sharedActors := make([]int64, 0, len(similarities))
+	sharedGenres := make([]int64, 0, len(similarities))
+	for _, s := range similarities {
+		movieIDs = append(movieIDs, int64(s.movieID))
+		similarIDs = append(similarIDs, int64(s.similarID))
+		scores = append(scores, s.score)
+		sharedActors = append(sharedActors, int64(s.sharedActors))
+		sharedGenres = append(sharedGenres, int64(s.sharedGenres))
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM movie_similarities`); err != nil {
+		return 0, err
+	}
+	// Фильмы, удалённые во время расчёта, пропускаются.
+	result, err := tx.Exec(`
+		INSERT INTO movie_similarities (movie_id, similar_id, score, shared_actors, shared_genres)
+		SELECT u.movie_id, u.similar_id, u.score, u.shared_actors, u.shared_genres
+		FROM unnest($1::int[], $2::int[], $3::float8[], $4::int[], $5::int[])
+			AS u(movie_id, similar_id, score, shared_actors, shared_genres)
+		WHERE EXISTS (SELECT 1 FROM movies WHERE id = u.movie_id)
+		  AND EXISTS (SELECT 1 FROM movies WHERE id = u.similar_id)
+	`, pq.Array(movieIDs), pq.Array(similarIDs), pq.Array(scores), pq.Array(sharedActors), pq.Array(sharedGenres))
+	if err != nil {
+		return 0, convertError(err)
+	}
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(saved), tx.Commit()
+}
+
+func (p *Postgres) similarityInput() (similarityInput, error) {
+	in := similarityInput{
+		years:  make(map[int]int),
+		scores: make(map[int]map[int]int),
+	}
+
+	rows, err := p.db.Query(`SELECT id, EXTRACT(YEAR FROM release_date)::int FROM movies`)
+	if err != nil {
+		return similarityInput{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var movieID int
+		var year sql.NullInt64
+		if err := rows.Scan(&movieID, &year); err != nil {
+			return similarityInput{}, err
+		}
+		in.years[movieID] = int(year.Int64)
+	}
+	if err := rows.Err(); err != nil {
+		return similarityInput{}, err
+	}
+
+	if in.cast, err = queryLinks(p.db, `SELECT movie_id, actor_id FROM movie_actors`); err != nil {
+		return similarityInput{}, err
+	}
+	if in.genres, err = queryLinks(p.db, `SELECT movie_id, genre_id FROM movie_genres`); err != nil {
+		return similarityInput{}, err
+	}
+
+	scoreRows, err := p.db.Query(`SELECT user_id, movie_id, score FROM reviews WHERE status = 'published'`)
+	if err != nil {
+		return similarityInput{}, err
+	}
+	defer scoreRows.Close()
+	for scoreRows.Next() {
+		var userID, movieID, score int
+		if err := scoreRows.Scan(&userID, &movieID, &score); err != nil {
+			return similarityInput{}, err
+		}
+		if in.scores[userID] == nil {
+			in.scores[userID] = make(map[int]int)
+		}
+		in.scores[userID][movieID] = score
+	}
+
+	return in, scoreRows.Err()
+}
+
+// queryLinks читает пары (фильм, id) связующей таблицы.
+func queryLinks(q queryer, query string) (map[int][]int, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make(map[int][]int)
+	for rows.Next() {
+		var movieID, id int
+		if err := rows.Scan(&movieID, &id); err != nil {
+			return nil, err
+		}
+		links[movieID] = append(links[movieID], id)
+	}
+
+	return links, rows.Err()
+}
+
+// GetSimilarMovies возвращает до limit фильмов, похожих на movieID, по
+// последнему расчёту RebuildSimilarities.
+func (p *Postgres) GetSimilarMovies(movieID, limit int) ([]SimilarMovie, error) {
+	if _, err := p.GetMovie(movieID); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.db.Query(`
+		SELECT m.id, m.title, m.description, m.release_date, m.rating, ms.score, ms.shared_actors, ms.shared_genres
+		FROM movie_similarities ms
+		INNER JOIN movies m ON m.id = ms.similar_id
+		WHERE ms.movie_id = $1
+		ORDER BY ms.score DESC, m.id
+		LIMIT $2
+	`, movieID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	similar := []SimilarMovie{}
+	for rows.Next() {
+		var movie SimilarMovie
+		if err := rows.Scan(append(movie.fields(), &movie.Score, &movie.SharedActors, &movie.SharedGenres)...); err != nil {
+			return nil, err
+		}
+		similar = append(similar, movie)
+	}
+
+	return similar, rows.Err()
+}
+
+// GetRecommendations ранжирует фильмы, похожие на те, что пользователь
+// оценил, посмотрел или отложил. Сами эти фильмы в выдачу не попадают.
+func (p *Postgres) GetRecommendations(userID, limit int) ([]Recommendation, error) {
+	rows, err := p.db.Query(`
+		WITH signals AS (
+			SELECT movie_id, (score - 5) / 5.0 AS weight, 1 AS priority FROM reviews WHERE user_id = $1
+			UNION ALL
+			SELECT movie_id, (score - 5) / 5.0, 2 FROM watched WHERE user_id = $1 AND score IS NOT NULL
+			UNION ALL
+			SELECT movie_id, $3, 3 FROM watched WHERE user_id = $1 AND score IS NULL
+			UNION ALL
+			SELECT movie_id, $4, 3 FROM watchlist WHERE user_id = $1
+		), seeds AS (
+			SELECT DISTINCT ON (movie_id) movie_id, weight
+			FROM signals
+			ORDER BY movie_id, priority, weight DESC
+		)
+		SELECT m.id, m.title, m.description, m.release_date, m.rating, sum(s.weight * ms.score) AS score
+		FROM seeds s
+		INNER JOIN movie_similarities ms ON ms.movie_id = s.movie_id
+		INNER JOIN movies m ON m.id = ms.similar_id
+		WHERE ms.similar_id NOT IN (SELECT movie_id FROM seeds)
+		GROUP BY m.id
+		HAVING sum(s.weight * ms.score) > 0
+		ORDER BY score DESC, m.id
+		LIMIT $2
+	`, userID, limit, watchedSignal, watchlistSignal)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recommendations := []Recommendation{}
+	for rows.Next() {
+		var r Recommendation
+		if err := rows.Scan(append(r.fields(), &r.Score)...); err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, r)
+	}
+
+	return recommendations, rows.Err()
+}
@@ -0,0 +1,304 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Статусы отзыва. Скрытые модератором отзывы не показываются другим
+// пользователям и не учитываются в рейтинге.
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+)
+
+const (
+	minScore = 0
+	maxScore = 10
+)
+
+var (
+	ErrUnknownReviewStatus = errors.New("unknown review status")
+	ErrInvalidScore        = errors.New("score out of range")
+)
+
+// Review - оценка фильма пользователем и необязательный текст отзыва. У
+// пользователя не больше одного отзыва на фильм.
+type Review struct {
+	ID        int       `json:"id"`
+	MovieID   int       `json:"movie_id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Score     int       `json:"score"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RatingStats - сводка оценок фильма. Rating совпадает с movies.rating.
+type RatingStats struct {
+	Votes  int      `json:"votes"`
+	Mean   float64  `json:"mean"`
+	Rating *float64 `json:"rating"`
+}
+
+// RatingPrior задаёт байесовское сглаживание рейтинга: оценки фильма
+// дополняются MinVotes голосами со средним Mean. При MinVotes = 0 рейтинг -
+// простое среднее опубликованных оценок.
+type RatingPrior struct {
+	MinVotes int
+	Mean     float64
+}
+
+// rating возвращает рейтинг фильма; без опубликованных оценок и без
+// сглаживания рейтинга нет (nil).
+func (p RatingPrior) rating(votes int, mean float64) *float64 {
+	if p.MinVotes <= 0 {
+		if votes == 0 {
+			return nil
+		}
+		return &mean
+	}
+	rating := (float64(votes)*mean + float64(p.MinVotes)*p.Mean) / float64(votes+p.MinVotes)
+	return &rating
+}
+
+// Unrated возвращает рейтинг нового фильма: среднее prior при сглаживании,
+// иначе nil.
+func (p RatingPrior) Unrated() *float64 {
+	return p.rating(0, 0)
+}
+
+var sortByNewest = Sort{Column: "id", Desc: true}
+
+func reviewKey(review Review) (string, int) {
+	return "", review.ID
+}
+
+const reviewColumns = `r.id, r.movie_id, r.user_id, u.username, r.score, r.body, r.status, r.created_at, r.updated_at`
+
+func scanReview(row interface{ Scan(...interface{}) error }, review *Review) error {
+	return row.Scan(&review.ID, &review.MovieID, &review.UserID, &review.Username, &review.Score,
+		&review.Body, &review.Status, &review.CreatedAt, &review.UpdatedAt)
+}
+
+func queryReview(q queryer, where string, args ...interface{}) (Review, error) {
+	var review Review
+	err := scanReview(q.QueryRow(`
+		SELECT `+reviewColumns+`
+		FROM reviews r
+		INNER JOIN users u ON u.id = r.user_id
+		WHERE `+where, args...), &review)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, ErrNotFound
+	}
+	return review, err
+}
+
+// recalculateRatingQuery пересчитывает сводку оценок фильма $1 с
+// параметрами сглаживания $2 (MinVotes) и $3 (Mean). Типы параметров указаны
+// явно: иначе Postgres выводит из $2 > 0 целый тип и для $3, и дробное
+// среднее не проходит.
+const recalculateRatingQuery = `
+	UPDATE movies m
+	SET vote_count = s.votes,
+	    vote_mean = s.mean,
+	    rating = CASE
+	        WHEN $2::int > 0 THEN (s.votes * s.mean + $2::int * $3::float8) / (s.votes + $2::int)
+	        WHEN s.votes > 0 THEN s.mean
+	    END
+	FROM (
+		SELECT count(*) AS votes, coalesce(avg(score), 0) AS mean
+		FROM reviews
+		WHERE movie_id = $1::int AND status = 'published'
+	) s
+	WHERE m.id = $1::int
+`
+
+// recalculateRating обновляет сводку оценок фильма; вызывается в транзакции,
+// изменившей отзывы фильма. Без оценок и без сглаживания рейтинг - NULL,
+// как в RatingPrior.rating.
+func recalculateRating(tx *sql.Tx, movieID int, prior RatingPrior) error {
+	_, err := tx.Exec(recalculateRatingQuery, movieID, prior.MinVotes, prior.Mean)
+	return err
+}
+
+// SaveReview создаёт или заменяет отзыв пользователя о фильме и пересчитывает
+// рейтинг фильма. created сообщает, был ли отзыв создан. Статус отзыва при
+// редактировании не меняется.
+func (p *Postgres) SaveReview(review Review, prior RatingPrior) (Review, bool, error) {
+	if err := checkReview(review); err != nil {
+		return Review{}, false, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return Review{}, false, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, review.MovieID); err != nil {
+		return Review{}, false, err
+	}
+
+	var created bool
+	err = tx.QueryRow(`
+		INSERT INTO reviews (movie_id, user_id, score, body)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (movie_id, user_id) DO UPDATE
+		SET score = EXCLUDED.score, body = EXCLUDED.body, updated_at = now()
+		RETURNING id, xmax = 0
+	`, review.MovieID, review.UserID, review.Score, review.Body).Scan(&review.ID, &created)
+	if err != nil {
+		return Review{}, false, convertError(err)
+	}
+
+	if err := recalculateRating(tx, review.MovieID, prior); err != nil {
+		return Review{}, false, err
+	}
+
+	saved, err := queryReview(tx, `r.id = $1`, review.ID)
+	if err != nil {
+		return Review{}, false, err
+	}
+
+	return saved, created, tx.Commit()
+}
+
+func (p *Postgres) GetUserReview(movieID, userID int) (Review, error) {
+	return queryReview(p.db, `r.movie_id = $1 AND r.user_id = $2`, movieID, userID)
+}
+
+func (p *Postgres) DeleteUserReview(movieID, userID int, prior RatingPrior) error {
+	return p.deleteReview(movieID, `movie_id = $1 AND user_id = $2`, prior, movieID, userID)
+}
+
+// DeleteReview удаляет отзыв по решению модератора.
+func (p *Postgres) DeleteReview(reviewID int, prior RatingPrior) error {
+	var movieID int
+	err := p.db.QueryRow(`SELECT movie_id FROM reviews WHERE id = $1`, reviewID).Scan(&movieID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.deleteReview(movieID, `id = $1`, prior, reviewID)
+}
+
+func (p *Postgres) deleteReview(movieID int, where string, prior RatingPrior, args ...interface{}) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM reviews WHERE `+where, args...)
+	if err != nil {
+		return convertError(err)
+	}
+	if err := checkAffected(result); err != nil {
+		return err
+	}
+
+	if err := recalculateRating(tx, movieID, prior); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetReviewStatus публикует или скрывает отзыв и пересчитывает рейтинг фильма.
+func (p *Postgres) SetReviewStatus(reviewID int, status string, prior RatingPrior) (Review, error) {
+	if status != ReviewPublished && status != ReviewHidden {
+		return Review{}, fmt.Errorf("%w: %q", ErrUnknownReviewStatus, status)
+	}
+
+	var movieID int
+	err := p.db.QueryRow(`SELECT movie_id FROM reviews WHERE id = $1`, reviewID).Scan(&movieID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, ErrNotFound
+	}
+	if err != nil {
+		return Review{}, err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return Review{}, err
+	}
+	defer tx.Rollback()
+
+	if err := lockMovie(tx, movieID); err != nil {
+		return Review{}, err
+	}
+
+	result, err := tx.Exec(`UPDATE reviews SET status = $2 WHERE id = $1`, reviewID, status)
+	if err != nil {
+		return Review{}, convertError(err)
+	}
+	if err := checkAffected(result); err != nil {
+		return Review{}, err
+	}
+
+	if err := recalculateRating(tx, movieID, prior); err != nil {
+		return Review{}, err
+	}
+
+	review, err := queryReview(tx, `r.id = $1`, reviewID)
+	if err != nil {
+		return Review{}, err
+	}
+
+	return review, tx.Commit()
+}
+
+// GetMovieReviews возвращает отзывы о фильме, новые первыми. Скрытые отзывы
+// попадают в выборку только при includeHidden.
+func (p *Postgres) GetMovieReviews(movieID int, includeHidden bool, page Page) ([]Review, PageInfo, error) {
+	if _, err := p.GetMovie(movieID); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM reviews r
+		INNER JOIN users u ON u.id = r.user_id
+		WHERE r.movie_id = $1 AND ($2 OR r.status = 'published')
+	`
+
+	return queryPage(p.db, query, []interface{}{movieID, includeHidden}, sortByNewest, page, reviewKey,
+		func(rows *sql.Rows, review *Review) error {
+			return scanReview(rows, review)
+		})
+}
+
+func (p *Postgres) GetMovieRatingStats(movieID int) (RatingStats, error) {
+	var stats RatingStats
+	err := p.db.QueryRow(`SELECT vote_count, vote_mean, rating FROM movies WHERE id = $1`, movieID).
+		Scan(&stats.Votes, &stats.Mean, &stats.Rating)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RatingStats{}, ErrNotFound
+	}
+	return stats, err
+}
+
+func checkReview(review Review) error {
+	return checkScore(review.Score)
+}
+
+// checkScore проверяет оценку фильма: в отзыве или в журнале просмотров.
+func checkScore(score int) error {
+	if score < minScore || score > maxScore {
+		return fmt.Errorf("%w: %d", ErrInvalidScore, score)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"math"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRatingPrior(t *testing.T) {
+	tests := []struct {
+		name  string
+		prior RatingPrior
+		votes int
+		mean  float64
+		want  *float64
+	}{
+		{"без оценок и сглаживания", RatingPrior{}, 0, 0, nil},
+		{"простое среднее", RatingPrior{}, 3, 7.5, ratingOf(7.5)},
+		{"без оценок со сглаживанием", RatingPrior{MinVotes: 10, Mean: 6.5}, 0, 0, ratingOf(6.5)},
+		{"дробное среднее сглаживания", RatingPrior{MinVotes: 2, Mean: 6.5}, 2, 9, ratingOf(7.75)},
+		{"одна оценка", RatingPrior{MinVotes: 3, Mean: 5.25}, 1, 10, ratingOf(6.4375)},
+	}
+	for _, tt := range tests {
+		got := tt.prior.rating(tt.votes, tt.mean)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("%s: рейтинг %v, ожидался nil", tt.name, *got)
+		case tt.want != nil && (got == nil || math.Abs(*got-*tt.want) > 1e-9):
+			t.Errorf("%s: рейтинг %v, ожидался %v", tt.name, got, *tt.want)
+		}
+	}
+}
+
+// TestRecalculateRatingQueryCasts проверяет, что у каждого параметра запроса
+// пересчёта указан тип: без приведения Postgres выводит для $3 тип integer
+// из соседнего $2, и дробное Mean ломает сохранение отзывов.
+func TestRecalculateRatingQueryCasts(t *testing.T) {
+	params := regexp.MustCompile(`\$\d+(::\w+)?`).FindAllStringSubmatch(recalculateRatingQuery, -1)
+	if len(params) == 0 {
+		t.Fatal("в запросе нет параметров")
+	}
+	for _, param := range params {
+		if param[1] == "" {
+			t.Errorf("параметр %s без явного типа", param[0])
+		}
+	}
+}
+
+func TestMemorySmoothedRating(t *testing.T) {
+	m := NewMemory()
+	prior := RatingPrior{MinVotes: 2, Mean: 6.5}
+	movie, err := m.AddMovie(Movie{Title: "Фильм", ReleaseDate: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userIDs []int
+	for _, username := range []string{"первый", "второй"} {
+		if err := m.AddUser(User{Username: username}); err != nil {
+			t.Fatal(err)
+		}
+		user, err := m.GetUserByUsername(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, user.Id)
+	}
+
+	steps := []struct {
+		name   string
+		change func() error
+		votes  int
+		rating float64
+	}{
+		{"первая оценка", func() error {
+			_, _, err := m.SaveReview(Review{MovieID: movie.ID, UserID: userIDs[0], Score: 9}, prior)
+			return err
+		}, 1, (9 + 2*6.5) / 3},
+		{"вторая оценка", func() error {
+			_, _, err := m.SaveReview(Review{MovieID: movie.ID, UserID: userIDs[1], Score: 8}, prior)
+			return err
+		}, 2, (17 + 2*6.5) / 4},
+		{"удаление оценки", func() error {
+			return m.DeleteUserReview(movie.ID, userIDs[0], prior)
+		}, 1, (8 + 2*6.5) / 3},
+		{"удаление последней оценки", func() error {
+			return m.DeleteUserReview(movie.ID, userIDs[1], prior)
+		}, 0, 6.5},
+	}
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		stats, err := m.GetMovieRatingStats(movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Votes != step.votes || stats.Rating == nil || math.Abs(*stats.Rating-step.rating) > 1e-9 {
+			t.Errorf("%s: %d оценок, рейтинг %v, ожидалось %d и %v", step.name, stats.Votes, stats.Rating, step.votes, step.rating)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// MovieSearchHit - фильм из результатов полнотекстового поиска.
+type MovieSearchHit struct {
+	Movie
+	Rank       float64         `json:"rank"`
+	Highlights MovieHighlights `json:"highlights"`
+}
+
+// MovieHighlights содержит фрагменты с найденными словами, выделенными <b></b>.
+type MovieHighlights struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Actors      []string `json:"actors,omitempty"`
+}
+
+// ActorSearchHit - актёр из результатов полнотекстового поиска.
+type ActorSearchHit struct {
+	Actor
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+var sortByRank = Sort{Column: "rank", Desc: true}
+
+func movieHitKey(hit MovieSearchHit) (string, int) {
+	return strconv.FormatFloat(hit.Rank, 'g', -1, 64), hit.ID
+}
+
+func actorHitKey(hit ActorSearchHit) (string, int) {
+	return strconv.FormatFloat(hit.Rank, 'g', -1, 64), hit.Id
+}
+
+// searchQueries разбирает строку поиска в синтаксисе websearch_to_tsquery:
+// query - для названий и описаний (русский и английский словари), names - для имён.
+const searchQueries = `
+	SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query,
+	       websearch_to_tsquery('simple', $1) AS names
+`
+
+// movieSearchQuery находит фильмы по названию, описанию и именам актёров; $1 -
+// строка поиска.
+const movieSearchQuery = `
+	WITH q AS (` + searchQueries + `),
+	actor_hits AS (
+		SELECT ma.movie_id, max(ts_rank(a.search_vector, q.names)) AS rank
+		FROM actors a
+		CROSS JOIN q
+		INNER JOIN movie_actors ma ON ma.actor_id = a.id
+		WHERE a.search_vector @@ q.names
+		GROUP BY ma.movie_id
+	)
+	SELECT m.id, m.title, m.description, m.release_date, m.rating,
+	       ts_rank(m.search_vector, q.query) + coalesce(ah.rank, 0) AS rank
+	FROM movies m
+	CROSS JOIN q
+	LEFT JOIN actor_hits ah ON ah.movie_id = m.id
+	WHERE m.id IN (
+		SELECT id FROM movies, q WHERE search_vector @@ q.query
+		UNION
+		SELECT movie_id FROM actor_hits
+	)
+`
+
+// SearchMovies ищет фильмы по названию, описанию и именам актёров и
+// упорядочивает их по релевантности.
+func (p *Postgres) SearchMovies(text string, page Page) ([]MovieSearchHit, PageInfo, error) {
+	if err := page.check(sortByRank); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := movieSearchQuery
+	args := []interface{}{text}
+
+	var total int
+	if err := p.db.QueryRow(countQuery(query), args...).Scan(&total); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	pageQuery, pageArgs := paginateQuery(query, args, sortByRank, page)
+	rows, err := p.db.Query(pageQuery, pageArgs...)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer rows.Close()
+
+	var hits []MovieSearchHit
+	for rows.Next() {
+		var hit MovieSearchHit
+		if err := rows.Scan(append(hit.fields(), &hit.Rank)...); err != nil {
+			return nil, PageInfo{}, err
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	reverse(hits, page)
+	hits, info := pageInfo(hits, page, sortByRank, total, movieHitKey)
+
+	// Фрагменты строятся отдельным запросом только для строк страницы:
+	// ts_headline заметно дороже самого поиска.
+	if err := p.highlightMovies(text, hits); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	return hits, info, nil
+}
+
+func (p *Postgres) highlightMovies(text string, hits []MovieSearchHit) error {
+	if len(hits) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	query := `
+		WITH q AS (` + searchQueries + `)
+		SELECT m.id,
+		       ts_headline('russian', m.title, q.query, 'HighlightAll=true'),
+		       ts_headline('russian', coalesce(m.description, ''), q.query, 'MaxFragments=2, MinWords=5, MaxWords=20'),
+		       coalesce((
+		           SELECT array_agg(ts_headline('simple', a.name, q.names, 'HighlightAll=true') ORDER BY a.id)
+		           FROM movie_actors ma
+		           INNER JOIN actors a ON a.id = ma.actor_id
+		           WHERE ma.movie_id = m.id AND a.search_vector @@ q.names
+		       ), '{}')
+		FROM movies m
+		CROSS JOIN q
+		WHERE m.id = ANY($2)
+	`
+
+	rows, err := p.db.Query(query, text, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	highlights := make(map[int]MovieHighlights, len(hits))
+	for rows.Next() {
+		var id int
+		var h MovieHighlights
+		if err := rows.Scan(&id, &h.Title, &h.Description, pq.Array(&h.Actors)); err != nil {
+			return err
+		}
+		highlights[id] = h
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range hits {
+		hits[i].Highlights = highlights[hits[i].ID]
+	}
+
+	return nil
+}
+
+// SearchActors ищет актёров по словам имени и упорядочивает их по релевантности.
+func (p *Postgres) SearchActors(text string, page Page) ([]ActorSearchHit, PageInfo, error) {
+	if err := page.check(sortByRank); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := `
+		SELECT a.id, a.name, a.gender, a.birthdate,
+		       ts_rank(a.search_vector, q.names) AS rank,
+		       ts_headline('simple', a.name, q.names, 'HighlightAll=true') AS highlight
+		FROM actors a
+		CROSS JOIN (` + searchQueries + `) q
+		WHERE a.search_vector @@ q.names
+	`
+	args := []interface{}{text}
+
+	var total int
+	if err := p.db.QueryRow(countQuery(query), args...).Scan(&total); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	pageQuery, pageArgs := paginateQuery(query, args, sortByRank, page)
+	rows, err := p.db.Query(pageQuery, pageArgs...)
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+	defer rows.Close()
+
+	var hits []ActorSearchHit
+	for rows.Next() {
+		var hit ActorSearchHit
+		if err := rows.Scan(append(hit.fields(), &hit.Rank, &hit.Highlight)...); err != nil {
+			return nil, PageInfo{}, err
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	reverse(hits, page)
+	hits, info := pageInfo(hits, page, sortByRank, total, actorHitKey)
+
+	return hits, info, nil
+}
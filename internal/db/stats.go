@@ -0,0 +1,287 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Источники оценок для гистограммы рейтинга.
+const (
+	RatingSourceMovies  = "movies"
+	RatingSourceReviews = "reviews"
+)
+
+const (
+	// ratingBuckets делит шкалу оценок от minScore до maxScore на отрезки по
+	// одному баллу; максимальная оценка попадает в последний отрезок.
+	ratingBuckets = maxScore - minScore
+	// ageBucketYears - ширина отрезка гистограммы возраста актёров.
+	ageBucketYears = 10
+)
+
+var ErrUnknownRatingSource = errors.New("unknown rating source")
+
+// PeriodStats - число фильмов и средний рейтинг за год или десятилетие.
+// Period - год или первый год десятилетия.
+type PeriodStats struct {
+	Period        int     `json:"period"`
+	Movies        int     `json:"movies"`
+	AverageRating float64 `json:"average_rating"`
+}
+
+// GenreStats - число фильмов жанра и их средний рейтинг.
+type GenreStats struct {
+	Genre
+	Movies        int     `json:"movies"`
+	AverageRating float64 `json:"average_rating"`
+}
+
+// ActorStats - актёр и число фильмов с его участием.
+type ActorStats struct {
+	Actor
+	Movies int `json:"movies"`
+}
+
+// HistogramBucket - число значений в полуинтервале [From, To). Последний
+// отрезок гистограммы включает и правую границу.
+type HistogramBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// AgeStats - возраст актёров на дату выхода фильма по всем ролям выборки.
+// Роли актёров без даты рождения и фильмов без даты выхода не учитываются.
+type AgeStats struct {
+	Credits    int               `json:"credits"`
+	AverageAge float64           `json:"average_age"`
+	MinAge     int               `json:"min_age"`
+	MaxAge     int               `json:"max_age"`
+	Histogram  []HistogramBucket `json:"histogram"`
+}
+
+// filteredMovies возвращает запрос фильмов, подходящих под filter, для
+// подстановки в FROM. Параметры нумеруются с $1.
+func filteredMovies(filter MovieFilter) (string, []interface{}) {
+	where, args := filter.where(nil)
+	return `(SELECT * FROM movies` + where + `)`, args
+}
+
+// GetMoviesByPeriod группирует фильмы по годам выхода, если years = 1, или по
+// отрезкам в years лет. Фильмы без даты выхода не учитываются.
+func (p *Postgres) GetMoviesByPeriod(filter MovieFilter, years int) ([]PeriodStats, error) {
+	movies, args := filteredMovies(filter)
+	args = append(args, years)
+	yearsParam := "$" + strconv.Itoa(len(args))
+	rows, err := p.db.Query(`
+		SELECT extract(year FROM m.release_date)::int / `+yearsParam+` * `+yearsParam+` AS period,
+			count(*), COALESCE(avg(m.rating), 0)
+		FROM `+movies+` m
+		WHERE m.release_date IS NOT NULL
+		GROUP BY period
+		ORDER BY period
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []PeriodStats{}
+	for rows.Next() {
+		var period PeriodStats
+		if err := rows.Scan(&period.Period, &period.Movies, &period.AverageRating); err != nil {
+			return nil, err
+		}
+		stats = append(stats, period)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetGenreStats возвращает все жанры, в том числе без фильмов в выборке,
+// начиная с самых многочисленных.
+func (p *Postgres) GetGenreStats(filter MovieFilter) ([]GenreStats, error) {
+	movies, args := filteredMovies(filter)
+	rows, err := p.db.Query(`
+		SELECT g.id, g.name, count(m.id) AS movies, COALESCE(avg(m.rating), 0)
+		FROM genres g
+		LEFT JOIN movie_genres mg ON mg.genre_id = g.id
+		LEFT JOIN `+movies+` m ON m.id = mg.movie_id
+		GROUP BY g.id
+		ORDER BY movies DESC, g.name, g.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []GenreStats{}
+	for rows.Next() {
+		var genre GenreStats
+		if err := rows.Scan(&genre.ID, &genre.Name, &genre.Movies, &genre.AverageRating); err != nil {
+			return nil, err
+		}
+		stats = append(stats, genre)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetProlificActors возвращает до limit актёров с наибольшим числом фильмов.
+func (p *Postgres) GetProlificActors(filter MovieFilter, limit int) ([]ActorStats, error) {
+	movies, args := filteredMovies(filter)
+	args = append(args, limit)
+	rows, err := p.db.Query(`
+		SELECT a.id, a.name, a.gender, a.birthdate, count(*) AS movies
+		FROM actors a
+		INNER JOIN movie_actors ma ON ma.actor_id = a.id
+		INNER JOIN `+movies+` m ON m.id = ma.movie_id
+		GROUP BY a.id
+		ORDER BY movies DESC, a.name, a.id
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []ActorStats{}
+	for rows.Next() {
+		var actor ActorStats
+		if err := rows.Scan(append(actor.fields(), &actor.Movies)...); err != nil {
+			return nil, err
+		}
+		stats = append(stats, actor)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetActorAgeStats считает возраст актёров в полных годах на дату выхода
+// фильма. Роли с датой рождения позже выхода фильма считаются ошибкой данных
+// и пропускаются.
+func (p *Postgres) GetActorAgeStats(filter MovieFilter) (AgeStats, error) {
+	movies, args := filteredMovies(filter)
+	rows, err := p.db.Query(`
+		SELECT extract(year FROM age(m.release_date, a.birthdate))::int AS age, count(*)
+		FROM `+movies+` m
+		INNER JOIN movie_actors ma ON ma.movie_id = m.id
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE m.release_date IS NOT NULL AND a.birthdate IS NOT NULL AND a.birthdate <= m.release_date
+		GROUP BY age
+		ORDER BY age
+	`, args...)
+	if err != nil {
+		return AgeStats{}, err
+	}
+	defer rows.Close()
+
+	ages := make(map[int]int)
+	for rows.Next() {
+		var age, count int
+		if err := rows.Scan(&age, &count); err != nil {
+			return AgeStats{}, err
+		}
+		ages[age] = count
+	}
+	if err := rows.Err(); err != nil {
+		return AgeStats{}, err
+	}
+
+	return ageStats(ages), nil
+}
+
+// GetRatingHistogram раскладывает по отрезкам в один балл рейтинги фильмов
+// или опубликованные оценки из отзывов на фильмы выборки.
+func (p *Postgres) GetRatingHistogram(filter MovieFilter, source string) ([]HistogramBucket, error) {
+	movies, args := filteredMovies(filter)
+	var query string
+	switch source {
+	case RatingSourceMovies:
+		query = `SELECT m.rating FROM ` + movies + ` m WHERE m.rating IS NOT NULL`
+	case RatingSourceReviews:
+		query = `
+			SELECT r.score FROM reviews r
+			INNER JOIN ` + movies + ` m ON m.id = r.movie_id
+			WHERE r.status = 'published'`
+	default:
+		return nil, ErrUnknownRatingSource
+	}
+
+	rows, err := p.db.Query(`
+		SELECT LEAST(floor(t.rating)::int, `+strconv.Itoa(maxScore-1)+`) AS bucket, count(*)
+		FROM (`+query+`) t (rating)
+		GROUP BY bucket
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histogram := ratingHistogram()
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		if i := bucket - minScore; i >= 0 && i < len(histogram) {
+			histogram[i].Count = count
+		}
+	}
+
+	return histogram, rows.Err()
+}
+
+// ratingHistogram возвращает пустые отрезки шкалы оценок.
+func ratingHistogram() []HistogramBucket {
+	histogram := make([]HistogramBucket, ratingBuckets)
+	for i := range histogram {
+		histogram[i] = HistogramBucket{From: float64(minScore + i), To: float64(minScore + i + 1)}
+	}
+	return histogram
+}
+
+// ratingBucket возвращает номер отрезка ratingHistogram для оценки rating
+// или -1, если оценка вне шкалы.
+func ratingBucket(rating float64) int {
+	if rating < minScore || rating > maxScore {
+		return -1
+	}
+	return min(int(math.Floor(rating))-minScore, ratingBuckets-1)
+}
+
+// ageStats сводит число ролей по возрасту в AgeStats с отрезками по
+// ageBucketYears лет от самого младшего до самого старшего возраста.
+func ageStats(ages map[int]int) AgeStats {
+	stats := AgeStats{Histogram: []HistogramBucket{}}
+	keys := sortedKeys(ages)
+	if len(keys) == 0 {
+		return stats
+	}
+
+	stats.MinAge, stats.MaxAge = keys[0], keys[len(keys)-1]
+	first := stats.MinAge / ageBucketYears * ageBucketYears
+	for from := first; from <= stats.MaxAge; from += ageBucketYears {
+		stats.Histogram = append(stats.Histogram, HistogramBucket{From: float64(from), To: float64(from + ageBucketYears)})
+	}
+
+	total := 0
+	for _, age := range keys {
+		stats.Credits += ages[age]
+		total += age * ages[age]
+		stats.Histogram[(age-first)/ageBucketYears].Count += ages[age]
+	}
+	stats.AverageAge = float64(total) / float64(stats.Credits)
+
+	return stats
+}
+
+// fullYears возвращает возраст в полных годах на дату at, как age() в Postgres.
+func fullYears(birthdate, at time.Time) int {
+	years := at.Year() - birthdate.Year()
+	if at.Month() < birthdate.Month() || (at.Month() == birthdate.Month() && at.Day() < birthdate.Day()) {
+		years--
+	}
+	return years
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrValueTooLong        = errors.New("value too long for column")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrDuplicateKey        = errors.New("duplicate key value")
+	ErrUnknownSortColumn   = errors.New("unknown sort column")
+)
+
+// MovieStore описывает операции над фильмами и связями фильм-актёр.
+type MovieStore interface {
+	AddMovie(movie Movie) (Movie, error)
+	UpdateMovie(movieID int, patch MoviePatch) (Movie, error)
+	ReplaceMovie(movie Movie) (Movie, error)
+	GetMovie(movieID int) (Movie, error)
+	DeleteMovie(movieID int) error
+	AddMovieActor(movieID, actorID int) error
+	GetMovieCast(movieID int) ([]CastMember, error)
+	ReplaceMovieCast(movieID int, cast []CastMember) ([]CastMember, error)
+	RemoveMovieActor(movieID, actorID int) ([]CastMember, error)
+	GetMovieCrew(movieID int) ([]CrewMember, error)
+	ReplaceMovieCrew(movieID int, crew []CrewMember) ([]CrewMember, error)
+	RemoveMovieCrewMember(movieID, personID int, job string) ([]CrewMember, error)
+	GetMovieGenres(movieID int) ([]Genre, error)
+	ReplaceMovieGenres(movieID int, genreIDs []int) ([]Genre, error)
+	GetMovieGenreFacets(filter MovieFilter) ([]GenreFacet, error)
+	GetMoviesWithSorting(filter MovieFilter, orderBy, sortOrder string, page Page) ([]Movie, PageInfo, error)
+	SearchMoviesByActorName(actorName string, page Page) ([]Movie, PageInfo, error)
+	SearchMoviesByTitleOrActorName(titleFragment, actorNameFragment string, page Page) ([]Movie, PageInfo, error)
+	GetMoviesByActorName(actorName string, page Page) ([]MovieCredit, PageInfo, error)
+	GetMoviesByActorID(actorID int, page Page) ([]MovieCredit, PageInfo, error)
+	GetActorFilmography(actorID int) ([]MovieCredit, error)
+	GetPersonCrewCredits(personID int) ([]CrewCredit, error)
+	SearchMoviesByCrew(name, job string, page Page) ([]Movie, PageInfo, error)
+	SearchMoviesByCrewFuzzy(name, job string, threshold float64, page Page) ([]Movie, PageInfo, error)
+	SearchMovies(text string, page Page) ([]MovieSearchHit, PageInfo, error)
+	SearchMovieGenreFacets(text string) ([]GenreFacet, error)
+	SearchMoviesFuzzy(titleFragment, actorName string, threshold float64, page Page) ([]Movie, PageInfo, error)
+	SuggestMovies(text string, threshold float64, limit int) ([]Suggestion, error)
+}
+
+// ActorStore описывает операции над актёрами.
+type ActorStore interface {
+	AddActor(actor Actor) (Actor, error)
+	UpdateActor(actorID int, patch ActorPatch) (Actor, error)
+	ReplaceActor(actor Actor) (Actor, error)
+	GetActor(actorID int) (Actor, error)
+	DeleteActor(actorID int) error
+	GetActors(page Page) ([]Actor, PageInfo, error)
+	SearchActors(text string, page Page) ([]ActorSearchHit, PageInfo, error)
+	SuggestActors(text string, threshold float64, limit int) ([]Suggestion, error)
+	GetCoStars(actorID, limit int) ([]CoStar, error)
+	GetActorPath(fromID, toID, maxDepth int) (ActorPath, error)
+}
+
+// ReviewStore описывает отзывы пользователей. Методы, меняющие отзывы,
+// пересчитывают рейтинг фильма с учётом prior.
+type ReviewStore interface {
+	SaveReview(review Review, prior RatingPrior) (Review, bool, error)
+	GetUserReview(movieID, userID int) (Review, error)
+	DeleteUserReview(movieID, userID int, prior RatingPrior) error
+	DeleteReview(reviewID int, prior RatingPrior) error
+	SetReviewStatus(reviewID int, status string, prior RatingPrior) (Review, error)
+	GetMovieReviews(movieID int, includeHidden bool, page Page) ([]Review, PageInfo, error)
+	GetMovieRatingStats(movieID int) (RatingStats, error)
+}
+
+// ListStore описывает личные списки пользователя: «посмотреть позже», журнал
+// просмотров и именованные списки. Все методы, кроме GetPublicList, видят
+// только записи пользователя userID.
+type ListStore interface {
+	GetWatchlist(userID int, page Page) ([]WatchlistItem, PageInfo, error)
+	AddToWatchlist(userID, movieID int) (bool, error)
+	RemoveFromWatchlist(userID, movieID int) error
+	GetWatched(userID int, page Page) ([]WatchedEntry, PageInfo, error)
+	AddWatched(entry WatchedEntry) (WatchedEntry, error)
+	DeleteWatched(userID, entryID int) error
+	GetLists(userID int) ([]MovieList, error)
+	AddList(list MovieList) (MovieListDetail, error)
+	GetList(userID, listID int) (MovieListDetail, error)
+	UpdateList(list MovieList) (MovieListDetail, error)
+	DeleteList(userID, listID int) error
+	AddListMovie(userID, listID, movieID int) (MovieListDetail, error)
+	RemoveListMovie(userID, listID, movieID int) (MovieListDetail, error)
+	GetPublicList(slug string) (MovieListDetail, error)
+}
+
+// RecommendationStore описывает похожие фильмы и рекомендации. Похожесть
+// считается заранее в RebuildSimilarities, запросы только читают результат.
+type RecommendationStore interface {
+	RebuildSimilarities(weights SimilarityWeights) (int, error)
+	GetSimilarMovies(movieID, limit int) ([]SimilarMovie, error)
+	GetRecommendations(userID, limit int) ([]Recommendation, error)
+}
+
+// StatsStore описывает сводную статистику каталога. Фильтр ограничивает
+// выборку фильмов, по которой считаются показатели.
+type StatsStore interface {
+	GetMoviesByPeriod(filter MovieFilter, years int) ([]PeriodStats, error)
+	GetGenreStats(filter MovieFilter) ([]GenreStats, error)
+	GetProlificActors(filter MovieFilter, limit int) ([]ActorStats, error)
+	GetActorAgeStats(filter MovieFilter) (AgeStats, error)
+	GetRatingHistogram(filter MovieFilter, source string) ([]HistogramBucket, error)
+}
+
+// GenreStore описывает справочник жанров.
+type GenreStore interface {
+	AddGenre(genre Genre) (Genre, error)
+	UpdateGenre(genre Genre) error
+	GetGenre(genreID int) (Genre, error)
+	GetGenres() ([]Genre, error)
+	DeleteGenre(genreID int) error
+}
+
+// UserStore описывает операции над учётными записями и выданными токенами.
+type UserStore interface {
+	AddUser(user User) error
+	GetUserByUsername(username string) (User, error)
+	GetUserByToken(tokenHash string) (User, error)
+	SetUserRole(userID int, role string) error
+	AddToken(token Token) error
+	RevokeToken(tokenHash string) error
+	RevokeUserTokens(userID int) error
+}
+
+// Store объединяет хранилища, от которых зависит Filmoteka.
+type Store interface {
+	MovieStore
+	ActorStore
+	GenreStore
+	ReviewStore
+	ListStore
+	RecommendationStore
+	StatsStore
+	UserStore
+}
+
+var (
+	_ Store = (*Postgres)(nil)
+	_ Store = (*Memory)(nil)
+)
+
+// convertError приводит ошибки нарушения ограничений Postgres к ошибкам пакета,
+// чтобы обработчики не зависели от конкретного хранилища.
+func convertError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "23505":
+		return errors.Join(ErrDuplicateKey, err)
+	case "23503":
+		return errors.Join(ErrForeignKeyViolation, err)
+	case "22001":
+		return errors.Join(ErrValueTooLong, err)
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type User struct {
+	Id           int       `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Token - выданный пользователю bearer-токен. В базе хранится только хэш токена.
+type Token struct {
+	Id        int        `json:"id"`
+	UserId    int        `json:"user_id"`
+	Hash      string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (p *Postgres) AddUser(user User) error {
+	query := `
+        INSERT INTO users (username, password_hash, role)
+        VALUES ($1, $2, $3)
+    `
+
+	_, err := p.db.Exec(query, user.Username, user.PasswordHash, user.Role)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (p *Postgres) GetUserByUsername(username string) (User, error) {
+	query := `
+        SELECT id, username, password_hash, role, created_at
+        FROM users
+        WHERE username = $1
+    `
+
+	var user User
+	err := p.db.QueryRow(query, username).Scan(&user.Id, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func (p *Postgres) GetUserByToken(tokenHash string) (User, error) {
+	query := `
+        SELECT u.id, u.username, u.password_hash, u.role, u.created_at
+        FROM user_tokens t
+        INNER JOIN users u ON t.user_id = u.id
+        WHERE t.token_hash = $1 AND t.revoked_at IS NULL AND t.expires_at > now()
+    `
+
+	var user User
+	err := p.db.QueryRow(query, tokenHash).Scan(&user.Id, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func (p *Postgres) SetUserRole(userID int, role string) error {
+	query := `UPDATE users SET role = $2 WHERE id = $1`
+
+	result, err := p.db.Exec(query, userID, role)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return checkAffected(result)
+}
+
+func (p *Postgres) AddToken(token Token) error {
+	query := `
+        INSERT INTO user_tokens (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)
+    `
+
+	_, err := p.db.Exec(query, token.UserId, token.Hash, token.ExpiresAt)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (p *Postgres) RevokeToken(tokenHash string) error {
+	query := `UPDATE user_tokens SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL`
+
+	_, err := p.db.Exec(query, tokenHash)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
+
+func (p *Postgres) RevokeUserTokens(userID int) error {
+	query := `UPDATE user_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err := p.db.Exec(query, userID)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return nil
+}
@@ -6,23 +6,111 @@ import (
 )
 
 func (f *Filmoteka) Api() {
+	f.Logger.Info("Server start")
+	if err := http.ListenAndServe(":8080", f.Routes()); err != nil {
+		f.Logger.Error(fmt.Sprintf("Server error: %v", err))
+	}
+}
+
+// Routes собирает маршрутизатор приложения. Права доступа к маршрутам заданы в
+// routePermissions по тем же шаблонам, что и здесь.
+func (f *Filmoteka) Routes() http.Handler {
+	mux := http.NewServeMux()
 	authMiddleware := f.AuthMiddleware
 
-	http.Handle("/actors/add", authMiddleware(http.HandlerFunc(f.handleAddActor)))
-	http.Handle("/actors/update", authMiddleware(http.HandlerFunc(f.handleUpdateActor)))
-	http.Handle("/actors/delete", authMiddleware(http.HandlerFunc(f.handleDeleteActor)))
-	http.Handle("/movies/add", authMiddleware(http.HandlerFunc(f.handleAddMovie)))
-	http.Handle("/movies/update", authMiddleware(http.HandlerFunc(f.handleUpdateMovie)))
-	http.Handle("/movies/update_actors", authMiddleware(http.HandlerFunc(f.handleUpdateMovieActors)))
-	http.Handle("/movies/delete", authMiddleware(http.HandlerFunc(f.handleDeleteMovie)))
-	http.Handle("/movies", authMiddleware(http.HandlerFunc(f.handleGetMovies)))
-	http.Handle("/movies/search", authMiddleware(http.HandlerFunc(f.handleSearchMoviesByTitleOrActor)))
-	http.Handle("/movies/search_by_actor", authMiddleware(http.HandlerFunc(f.handleSearchMoviesByActorName)))
-	http.Handle("/actors", authMiddleware(http.HandlerFunc(f.handleGetActors)))
-	http.Handle("/actors/movies", authMiddleware(http.HandlerFunc(f.handleGetActorMovies)))
+	mux.Handle("GET /movies", authMiddleware(http.HandlerFunc(f.handleGetMovies)))
+	mux.Handle("POST /movies", authMiddleware(http.HandlerFunc(f.handleAddMovie)))
+	mux.Handle("GET /movies/search", authMiddleware(http.HandlerFunc(f.handleSearchMoviesByTitleOrActor)))
+	mux.Handle("GET /movies/autocomplete", authMiddleware(http.HandlerFunc(f.handleAutocompleteMovies)))
+	mux.Handle("GET /movies/{id}", authMiddleware(http.HandlerFunc(f.handleGetMovie)))
+	mux.Handle("PUT /movies/{id}", authMiddleware(http.HandlerFunc(f.handleReplaceMovie)))
+	mux.Handle("PATCH /movies/{id}", authMiddleware(http.HandlerFunc(f.handlePatchMovie)))
+	mux.Handle("DELETE /movies/{id}", authMiddleware(http.HandlerFunc(f.handleDeleteMovie)))
+	mux.Handle("GET /movies/{id}/cast", authMiddleware(http.HandlerFunc(f.handleGetMovieCast)))
+	mux.Handle("POST /movies/{id}/actors", authMiddleware(http.HandlerFunc(f.handleUpdateMovieActors)))
+	mux.Handle("PUT /movies/{id}/actors", authMiddleware(http.HandlerFunc(f.handleReplaceMovieCast)))
+	mux.Handle("DELETE /movies/{id}/actors/{actor_id}", authMiddleware(http.HandlerFunc(f.handleRemoveMovieActor)))
+	mux.Handle("GET /movies/{id}/crew", authMiddleware(http.HandlerFunc(f.handleGetMovieCrew)))
+	mux.Handle("PUT /movies/{id}/crew", authMiddleware(http.HandlerFunc(f.handleReplaceMovieCrew)))
+	mux.Handle("DELETE /movies/{id}/crew/{person_id}", authMiddleware(http.HandlerFunc(f.handleRemoveMovieCrewMember)))
+	mux.Handle("GET /movies/{id}/genres", authMiddleware(http.HandlerFunc(f.handleGetMovieGenres)))
+	mux.Handle("PUT /movies/{id}/genres", authMiddleware(http.HandlerFunc(f.handleReplaceMovieGenres)))
+	mux.Handle("GET /movies/{id}/reviews", authMiddleware(http.HandlerFunc(f.handleGetMovieReviews)))
+	mux.Handle("GET /movies/{id}/reviews/me", authMiddleware(http.HandlerFunc(f.handleGetMyReview)))
+	mux.Handle("PUT /movies/{id}/reviews/me", authMiddleware(http.HandlerFunc(f.handleSaveMyReview)))
+	mux.Handle("DELETE /movies/{id}/reviews/me", authMiddleware(http.HandlerFunc(f.handleDeleteMyReview)))
+	mux.Handle("GET /movies/{id}/similar", authMiddleware(http.HandlerFunc(f.handleGetSimilarMovies)))
 
-	f.Logger.Info("Server start")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		f.Logger.Error(fmt.Sprintf("Server error: %v", err))
+	mux.Handle("GET /actors", authMiddleware(http.HandlerFunc(f.handleGetActors)))
+	mux.Handle("POST /actors", authMiddleware(http.HandlerFunc(f.handleAddActor)))
+	mux.Handle("GET /actors/search", authMiddleware(http.HandlerFunc(f.handleSearchActors)))
+	mux.Handle("GET /actors/autocomplete", authMiddleware(http.HandlerFunc(f.handleAutocompleteActors)))
+	mux.Handle("GET /actors/{id}", authMiddleware(http.HandlerFunc(f.handleGetActor)))
+	mux.Handle("PUT /actors/{id}", authMiddleware(http.HandlerFunc(f.handleReplaceActor)))
+	mux.Handle("PATCH /actors/{id}", authMiddleware(http.HandlerFunc(f.handlePatchActor)))
+	mux.Handle("DELETE /actors/{id}", authMiddleware(http.HandlerFunc(f.handleDeleteActor)))
+	mux.Handle("GET /actors/{id}/movies", authMiddleware(http.HandlerFunc(f.handleGetActorMoviesByID)))
+	mux.Handle("GET /actors/{id}/costars", authMiddleware(http.HandlerFunc(f.handleGetCoStars)))
+	mux.Handle("GET /actors/{id}/path/{other_id}", authMiddleware(http.HandlerFunc(f.handleGetActorPath)))
+
+	mux.Handle("GET /stats/movies", authMiddleware(http.HandlerFunc(f.handleGetMovieStats)))
+	mux.Handle("GET /stats/genres", authMiddleware(http.HandlerFunc(f.handleGetGenreStats)))
+	mux.Handle("GET /stats/actors", authMiddleware(http.HandlerFunc(f.handleGetProlificActors)))
+	mux.Handle("GET /stats/actors/ages", authMiddleware(http.HandlerFunc(f.handleGetActorAgeStats)))
+	mux.Handle("GET /stats/ratings", authMiddleware(http.HandlerFunc(f.handleGetRatingHistogram)))
+
+	mux.Handle("GET /genres", authMiddleware(http.HandlerFunc(f.handleGetGenres)))
+	mux.Handle("POST /genres", authMiddleware(http.HandlerFunc(f.handleAddGenre)))
+	mux.Handle("GET /genres/{id}", authMiddleware(http.HandlerFunc(f.handleGetGenre)))
+	mux.Handle("PUT /genres/{id}", authMiddleware(http.HandlerFunc(f.handleReplaceGenre)))
+	mux.Handle("DELETE /genres/{id}", authMiddleware(http.HandlerFunc(f.handleDeleteGenre)))
+
+	mux.Handle("PATCH /reviews/{id}", authMiddleware(http.HandlerFunc(f.handleModerateReview)))
+	mux.Handle("DELETE /reviews/{id}", authMiddleware(http.HandlerFunc(f.handleDeleteReview)))
+
+	mux.Handle("GET /me/watchlist", authMiddleware(http.HandlerFunc(f.handleGetWatchlist)))
+	mux.Handle("PUT /me/watchlist/{movie_id}", authMiddleware(http.HandlerFunc(f.handleAddToWatchlist)))
+	mux.Handle("DELETE /me/watchlist/{movie_id}", authMiddleware(http.HandlerFunc(f.handleRemoveFromWatchlist)))
+	mux.Handle("GET /me/watched", authMiddleware(http.HandlerFunc(f.handleGetWatched)))
+	mux.Handle("POST /me/watched", authMiddleware(http.HandlerFunc(f.handleAddWatched)))
+	mux.Handle("DELETE /me/watched/{id}", authMiddleware(http.HandlerFunc(f.handleDeleteWatched)))
+	mux.Handle("GET /me/lists", authMiddleware(http.HandlerFunc(f.handleGetLists)))
+	mux.Handle("POST /me/lists", authMiddleware(http.HandlerFunc(f.handleAddList)))
+	mux.Handle("GET /me/lists/{id}", authMiddleware(http.HandlerFunc(f.handleGetList)))
+	mux.Handle("PUT /me/lists/{id}", authMiddleware(http.HandlerFunc(f.handleReplaceList)))
+	mux.Handle("DELETE /me/lists/{id}", authMiddleware(http.HandlerFunc(f.handleDeleteList)))
+	mux.Handle("PUT /me/lists/{id}/movies/{movie_id}", authMiddleware(http.HandlerFunc(f.handleAddListMovie)))
+	mux.Handle("DELETE /me/lists/{id}/movies/{movie_id}", authMiddleware(http.HandlerFunc(f.handleRemoveListMovie)))
+	mux.Handle("GET /me/recommendations", authMiddleware(http.HandlerFunc(f.handleGetRecommendations)))
+	mux.HandleFunc("GET /lists/{slug}", f.handleGetPublicList)
+	mux.Handle("POST /recommendations/rebuild", authMiddleware(http.HandlerFunc(f.handleRebuildRecommendations)))
+
+	mux.HandleFunc("POST /auth/register", f.handleRegister)
+	mux.HandleFunc("POST /auth/login", f.handleLogin)
+	mux.Handle("POST /auth/logout", authMiddleware(http.HandlerFunc(f.handleLogout)))
+	mux.Handle("POST /auth/permissions", authMiddleware(http.HandlerFunc(f.handleGetTokenPermissions)))
+	mux.Handle("POST /users/role", authMiddleware(http.HandlerFunc(f.handleSetUserRole)))
+	mux.Handle("POST /users/revoke_tokens", authMiddleware(http.HandlerFunc(f.handleRevokeUserTokens)))
+
+	if f.Config.HTTP.LegacyRoutes {
+		f.legacyRoutes(mux)
 	}
+
+	return RequestID(f.Localize(RouteErrors(mux)))
+}
+
+// legacyRoutes регистрирует маршруты первой версии API. Теперь они тоже
+// привязаны к HTTP-методам из specification.yaml.
+func (f *Filmoteka) legacyRoutes(mux *http.ServeMux) {
+	authMiddleware := f.AuthMiddleware
+
+	mux.Handle("POST /actors/add", authMiddleware(http.HandlerFunc(f.handleAddActor)))
+	mux.Handle("POST /actors/update", authMiddleware(http.HandlerFunc(f.handleUpdateActor)))
+	mux.Handle("DELETE /actors/delete", authMiddleware(http.HandlerFunc(f.handleDeleteActor)))
+	mux.Handle("POST /movies/add", authMiddleware(http.HandlerFunc(f.handleAddMovie)))
+	mux.Handle("POST /movies/update", authMiddleware(http.HandlerFunc(f.handleUpdateMovie)))
+	mux.Handle("POST /movies/update_actors", authMiddleware(http.HandlerFunc(f.handleUpdateMovieActors)))
+	mux.Handle("DELETE /movies/delete", authMiddleware(http.HandlerFunc(f.handleDeleteMovie)))
+	mux.Handle("GET /movies/search_by_actor", authMiddleware(http.HandlerFunc(f.handleSearchMoviesByActorName)))
+	mux.Handle("GET /actors/movies", authMiddleware(http.HandlerFunc(f.handleGetActorMovies)))
 }
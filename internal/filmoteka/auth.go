@@ -0,0 +1,365 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"TestVK/internal/jwt"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Режимы аутентификации: непрозрачные токены из базы, JWT или оба сразу.
+const (
+	AuthModeToken = "token"
+	AuthModeJWT   = "jwt"
+	AuthModeAny   = "any"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+const (
+	defaultTokenTTL   = 24 * time.Hour
+	minPasswordLength = 8
+	// maxPasswordBytes - предел bcrypt: более длинный пароль он не хэширует.
+	maxPasswordBytes  = 72
+	maxUsernameLength = 64
+	tokenBytes        = 32
+)
+
+// dummyPasswordHash - bcrypt-хэш с той же стоимостью, что у паролей
+// пользователей. Вход с неизвестным именем сверяет пароль с ним, чтобы по
+// времени ответа нельзя было узнать, существует ли пользователь.
+var dummyPasswordHash = []byte("$2a$10$q8OAopEM6tTIkfs2Z4Ii6uaBMIVAWILgDh.aSMxO1jkU65knlW8am")
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	requestIDContextKey
+	languageContextKey
+)
+
+type CredentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type PermissionsRequest struct {
+	Token string `json:"token"`
+}
+
+type PermissionsResponse struct {
+	User        db.User  `json:"user"`
+	Permissions []string `json:"permissions"`
+}
+
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserFromContext возвращает пользователя, аутентифицированного AuthMiddleware.
+func UserFromContext(ctx context.Context) (db.User, bool) {
+	user, ok := ctx.Value(userContextKey).(db.User)
+	return user, ok
+}
+
+// registeredUser возвращает пользователя, от имени которого выполняется запрос.
+// Служебный токен администратора и JWT без claim пользователя не связаны с
+// учётной записью, поэтому отзывы и личные списки им недоступны.
+func registeredUser(w http.ResponseWriter, r *http.Request) (db.User, bool) {
+	user, ok := UserFromContext(r.Context())
+	if !ok || user.Id == 0 {
+		writeError(w, r, codeForbidden, i18n.ActionRequiresAccount)
+		return db.User{}, false
+	}
+	return user, true
+}
+
+func (f *Filmoteka) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var credentials CredentialsRequest
+	decoded, ok := f.decodeBody(w, r, &credentials, i18n.InvalidBody)
+	if !ok {
+		return
+	}
+	if violations := mergeViolations(decoded, validate(credentials, registerRules)); len(violations) > 0 {
+		writeViolations(w, r, violations)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
+	if err != nil {
+		f.Logger.Warn("Error hashing password", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.RegisterError)
+		return
+	}
+
+	user := db.User{
+		Username:     credentials.Username,
+		PasswordHash: string(passwordHash),
+		Role:         "user",
+	}
+	if err := f.Store.AddUser(user); err != nil {
+		if errors.Is(err, db.ErrDuplicateKey) {
+			writeError(w, r, codeConflict, i18n.UserExists)
+			return
+		}
+		f.Logger.Warn("Error creating user", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.RegisterError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(message(r, i18n.UserRegistered)))
+	f.Logger.Info("New user", "username", user.Username)
+}
+
+func (f *Filmoteka) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var credentials CredentialsRequest
+	violations, ok := f.decodeBody(w, r, &credentials, i18n.InvalidBody)
+	if !ok {
+		return
+	}
+	if len(violations) > 0 {
+		writeViolations(w, r, violations)
+		return
+	}
+
+	user, err := f.Store.GetUserByUsername(credentials.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		f.Logger.Warn("Error getting user", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.LoginError)
+		return
+	}
+	passwordHash := []byte(user.PasswordHash)
+	if err != nil {
+		passwordHash = dummyPasswordHash
+	}
+	if bcrypt.CompareHashAndPassword(passwordHash, []byte(credentials.Password)) != nil || err != nil {
+		f.Logger.Info("Failed login", "username", credentials.Username)
+		writeError(w, r, codeInvalidCredentials, i18n.InvalidCredentials)
+		return
+	}
+
+	rawToken, err := newToken()
+	if err != nil {
+		f.Logger.Warn("Error generating token", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.LoginError)
+		return
+	}
+
+	ttl := f.Config.Auth.TokenTTL
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	token := db.Token{
+		UserId:    user.Id,
+		Hash:      hashToken(rawToken),
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+	}
+	if err := f.Store.AddToken(token); err != nil {
+		f.Logger.Warn("Error saving token", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.LoginError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TokenResponse{Token: rawToken, ExpiresAt: token.ExpiresAt})
+	f.Logger.Info("User logged in", "username", user.Username)
+}
+
+func (f *Filmoteka) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if err := f.Store.RevokeToken(hashToken(bearerToken(r))); err != nil {
+		f.Logger.Warn("Error revoking token", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.LogoutError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.TokenRevoked)))
+	user, _ := UserFromContext(r.Context())
+	f.Logger.Info("User logged out", "username", user.Username)
+}
+
+func (f *Filmoteka) handleRevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	idParam := r.URL.Query().Get("user_id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		f.Logger.Info("Can't get user id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidUserID)
+		return
+	}
+
+	if err := f.Store.RevokeUserTokens(userID); err != nil {
+		f.Logger.Warn("Error revoking user tokens", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.RevokeTokensError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.UserTokensRevoked)))
+	f.Logger.Info("User tokens revoked", "user_id", userID)
+}
+
+func (f *Filmoteka) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	idParam := r.URL.Query().Get("user_id")
+	userID, err := strconv.Atoi(idParam)
+	if err != nil {
+		f.Logger.Info("Can't get user id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidUserID)
+		return
+	}
+
+	role := r.URL.Query().Get("role")
+	if !f.Policy.HasRole(role) {
+		writeError(w, r, codeInvalidParameter, i18n.UnknownRole)
+		return
+	}
+
+	if err := f.Store.SetUserRole(userID, role); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, r, codeNotFound, i18n.UserNotFound)
+			return
+		}
+		f.Logger.Warn("Error setting user role", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.SetRoleError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.RoleChanged)))
+	f.Logger.Info("User role changed", "user_id", userID, "role", role)
+}
+
+func (f *Filmoteka) handleGetTokenPermissions(w http.ResponseWriter, r *http.Request) {
+	var permissionsReq PermissionsRequest
+	err := json.NewDecoder(r.Body).Decode(&permissionsReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := f.Authenticate(permissionsReq.Token)
+	if errors.Is(err, ErrUnauthenticated) || permissionsReq.Token == "" {
+		writeError(w, r, codeNotFound, i18n.TokenNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error authenticating token", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.PermissionsGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PermissionsResponse{User: user, Permissions: f.Policy.Permissions(user.Role)})
+	f.Logger.Info("Token permissions", "username", user.Username, "role", user.Role)
+}
+
+// Authenticate возвращает владельца токена. Токен администратора из конфигурации
+// продолжает работать как служебная учётная запись во всех режимах, кроме jwt.
+func (f *Filmoteka) Authenticate(token string) (db.User, error) {
+	mode := f.Config.Auth.Mode
+	if mode != AuthModeJWT && f.Config.Auth.Admin != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(f.Config.Auth.Admin)) == 1 {
+		return db.User{Username: "admin", Role: "admin"}, nil
+	}
+
+	if (mode == AuthModeJWT || mode == AuthModeAny) && jwt.LooksLikeJWT(token) {
+		return f.authenticateJWT(token)
+	}
+	if mode == AuthModeJWT {
+		return db.User{}, ErrUnauthenticated
+	}
+
+	user, err := f.Store.GetUserByToken(hashToken(token))
+	if errors.Is(err, db.ErrNotFound) {
+		return db.User{}, ErrUnauthenticated
+	}
+	return user, err
+}
+
+// authenticateJWT возвращает владельца JWT. Роль из токена должна быть
+// описана в политике. С учётной записью токен связывается только явно: claim
+// из auth_token.jwt.user_claim содержит имя локального пользователя; sub
+// внешнего издателя может случайно совпасть с чужим id.
+func (f *Filmoteka) authenticateJWT(token string) (db.User, error) {
+	if f.Verifier == nil {
+		return db.User{}, errors.New("jwt verifier is not configured")
+	}
+
+	claims, err := f.Verifier.Verify(token)
+	if err != nil {
+		return db.User{}, errors.Join(ErrUnauthenticated, err)
+	}
+
+	roleClaim := f.Config.Auth.JWT.RoleClaim
+	if roleClaim == "" {
+		roleClaim = "role"
+	}
+	role := claims.String(roleClaim)
+	if role == "" {
+		role = "user"
+	}
+	if !f.Policy.HasRole(role) {
+		return db.User{}, fmt.Errorf("%w: unknown role %q", ErrUnauthenticated, role)
+	}
+
+	user := db.User{Username: claims.Subject(), Role: role}
+	userClaim := f.Config.Auth.JWT.UserClaim
+	if userClaim == "" {
+		return user, nil
+	}
+	username := claims.String(userClaim)
+	if username == "" {
+		return user, nil
+	}
+
+	local, err := f.Store.GetUserByUsername(username)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.User{}, fmt.Errorf("%w: unknown user %q", ErrUnauthenticated, username)
+	}
+	if err != nil {
+		return db.User{}, err
+	}
+	user.Id, user.Username = local.Id, local.Username
+	return user, nil
+}
+
+// bearerToken достаёт токен из заголовка Authorization. Для совместимости
+// принимается и значение без префикса Bearer.
+func bearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return header
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,150 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// CastMemberRequest - элемент тела PUT /movies/{id}/actors. Можно передать
+// просто id актёра или объект с ролью и местом в титрах.
+type CastMemberRequest struct {
+	ActorID    int    `json:"actor_id"`
+	Character  string `json:"character"`
+	Billing    int    `json:"billing"`
+	CreditType string `json:"credit_type"`
+}
+
+func (c *CastMemberRequest) UnmarshalJSON(data []byte) error {
+	var actorID int
+	if err := json.Unmarshal(data, &actorID); err == nil {
+		*c = CastMemberRequest{ActorID: actorID}
+		return nil
+	}
+
+	type castMember CastMemberRequest
+	return json.Unmarshal(data, (*castMember)(c))
+}
+
+func (f *Filmoteka) handleGetMovieCast(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	cast, err := f.Store.GetMovieCast(movieID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting movie cast", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.CastGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cast)
+	f.Logger.Info("Movie cast", "movie_id", movieID, "actors", len(cast))
+}
+
+func (f *Filmoteka) handleReplaceMovieCast(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	var castReq []CastMemberRequest
+	err = json.NewDecoder(r.Body).Decode(&castReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	cast := make([]db.CastMember, 0, len(castReq))
+	for _, member := range castReq {
+		if member.ActorID <= 0 || member.Billing < 0 {
+			writeError(w, r, codeValidationFailed, i18n.InvalidCastMember)
+			return
+		}
+		cast = append(cast, db.CastMember{
+			ActorID:    member.ActorID,
+			Character:  member.Character,
+			Billing:    member.Billing,
+			CreditType: member.CreditType,
+		})
+	}
+
+	result, err := f.Store.ReplaceMovieCast(movieID, cast)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		case errors.Is(err, db.ErrForeignKeyViolation):
+			writeError(w, r, codeValidationFailed, i18n.ActorNotFound)
+		case errors.Is(err, db.ErrDuplicateKey):
+			writeError(w, r, codeValidationFailed, i18n.DuplicateCastMember)
+		case errors.Is(err, db.ErrValueTooLong):
+			writeFieldError(w, r, "character", i18n.CharacterTooLong)
+		case errors.Is(err, db.ErrUnknownCreditType):
+			writeFieldError(w, r, "credit_type", i18n.UnknownCreditType)
+		default:
+			f.Logger.Warn("Error replacing movie cast", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.CastUpdateError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	f.Logger.Info("Movie cast replaced", "movie_id", movieID, "actors", len(result))
+}
+
+func (f *Filmoteka) handleRemoveMovieActor(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+	actorID, err := strconv.Atoi(r.PathValue("actor_id"))
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+
+	cast, err := f.Store.RemoveMovieActor(movieID, actorID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.CastMemberNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error removing actor from movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.CastUpdateError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cast)
+	f.Logger.Info("Actor removed from movie", "movie_id", movieID, "actor_id", actorID)
+}
+
+// movieCredits оборачивает фильмы без сведений о роли актёра.
+func movieCredits(movies []db.Movie) []db.MovieCredit {
+	credits := make([]db.MovieCredit, 0, len(movies))
+	for _, movie := range movies {
+		credits = append(credits, db.MovieCredit{Movie: movie})
+	}
+	return credits
+}
@@ -0,0 +1,167 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CrewMemberRequest - элемент тела PUT /movies/{id}/crew. Людей съёмочной
+// группы, как и актёров, заводят через /actors.
+type CrewMemberRequest struct {
+	PersonID int    `json:"person_id"`
+	Job      string `json:"job"`
+}
+
+func (f *Filmoteka) handleGetMovieCrew(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	crew, err := f.Store.GetMovieCrew(movieID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting movie crew", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.CrewGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(crew)
+	f.Logger.Info("Movie crew", "movie_id", movieID, "crew", len(crew))
+}
+
+func (f *Filmoteka) handleReplaceMovieCrew(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	var crewReq []CrewMemberRequest
+	err = json.NewDecoder(r.Body).Decode(&crewReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	crew := make([]db.CrewMember, 0, len(crewReq))
+	for _, member := range crewReq {
+		crew = append(crew, db.CrewMember{PersonID: member.PersonID, Job: member.Job})
+	}
+
+	result, err := f.Store.ReplaceMovieCrew(movieID, crew)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		case errors.Is(err, db.ErrForeignKeyViolation):
+			writeError(w, r, codeValidationFailed, i18n.PersonNotFound)
+		case errors.Is(err, db.ErrDuplicateKey):
+			writeError(w, r, codeValidationFailed, i18n.DuplicateCrewMember)
+		case errors.Is(err, db.ErrUnknownJob):
+			writeFieldError(w, r, "job", i18n.UnknownJob)
+		default:
+			f.Logger.Warn("Error replacing movie crew", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.CrewUpdateError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+	f.Logger.Info("Movie crew replaced", "movie_id", movieID, "crew", len(result))
+}
+
+func (f *Filmoteka) handleRemoveMovieCrewMember(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+	personID, err := strconv.Atoi(r.PathValue("person_id"))
+	if err != nil {
+		f.Logger.Info("Can't get person id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPersonID)
+		return
+	}
+
+	crew, err := f.Store.RemoveMovieCrewMember(movieID, personID, r.URL.Query().Get("job"))
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.CrewMemberNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error removing crew member", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.CrewUpdateError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(crew)
+	f.Logger.Info("Crew member removed from movie", "movie_id", movieID, "person_id", personID)
+}
+
+// handleSearchMoviesByCrew ищет фильмы по имени человека из съёмочной группы:
+// director=имя - по режиссёру, crew_name=имя&job=должность - по любой должности.
+func (f *Filmoteka) handleSearchMoviesByCrew(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	name, job := strings.TrimSpace(queryValues.Get("crew_name")), queryValues.Get("job")
+	if director := strings.TrimSpace(queryValues.Get("director")); director != "" {
+		name, job = director, db.JobDirector
+	}
+	if name == "" {
+		writeError(w, r, codeInvalidParameter, i18n.CrewNameRequired)
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	fuzzy := fuzzySearch(r)
+	var movies []db.Movie
+	var info db.PageInfo
+	if fuzzy {
+		movies, info, err = f.Store.SearchMoviesByCrewFuzzy(name, job, f.similarityThreshold(), page)
+	} else {
+		movies, info, err = f.Store.SearchMoviesByCrew(name, job, page)
+	}
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if errors.Is(err, db.ErrUnknownJob) {
+		writeError(w, r, codeInvalidParameter, i18n.UnknownJob)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movie by crew", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MoviesByCrewError)
+		return
+	}
+
+	if info.Total == 0 && !fuzzy {
+		f.suggest(w, f.Store.SuggestActors, name)
+	}
+	writePage(w, r, page, info, movies)
+	f.Logger.Info("Movies by crew", "name", name, "job", job, "total", info.Total)
+}
@@ -0,0 +1,124 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var ErrUnknownField = errors.New("unknown field")
+
+var (
+	movieFields = []string{"id", "title", "description", "release_date", "rating"}
+	actorFields = []string{"id", "name", "gender", "birthdate"}
+)
+
+// MovieDetail - фильм вместе со встроенными связями.
+type MovieDetail struct {
+	db.Movie
+	Cast    []db.CastMember `json:"cast"`
+	Crew    []db.CrewMember `json:"crew"`
+	Genres  []db.Genre      `json:"genres"`
+	Ratings db.RatingStats  `json:"ratings"`
+}
+
+// ActorDetail - актёр вместе с фильмографией: ролями в movies и работой в
+// съёмочных группах в crew.
+type ActorDetail struct {
+	db.Actor
+	Movies []db.MovieCredit `json:"movies"`
+	Crew   []db.CrewCredit  `json:"crew"`
+}
+
+// expansion описывает форму ответа о ресурсе: какие связи встроить (expand)
+// и какие поля верхнего уровня оставить (fields).
+type expansion struct {
+	relations map[string]bool
+	fields    []string
+}
+
+// parseExpansion читает параметры expand и fields. Без expand встраиваются все
+// связи ресурса, пустое значение expand отключает их. Связь, не указанная в
+// fields, не загружается.
+func parseExpansion(r *http.Request, fields []string, relations ...string) (expansion, error) {
+	query := r.URL.Query()
+	e := expansion{relations: make(map[string]bool, len(relations))}
+
+	if query.Has("expand") {
+		for _, relation := range splitList(query.Get("expand")) {
+			if !slices.Contains(relations, relation) {
+				return expansion{}, fmt.Errorf("%w: expand=%s", ErrUnknownField, relation)
+			}
+			e.relations[relation] = true
+		}
+	} else {
+		for _, relation := range relations {
+			e.relations[relation] = true
+		}
+	}
+
+	if query.Has("fields") {
+		e.fields = splitList(query.Get("fields"))
+		for _, field := range e.fields {
+			if !slices.Contains(fields, field) && !slices.Contains(relations, field) {
+				return expansion{}, fmt.Errorf("%w: fields=%s", ErrUnknownField, field)
+			}
+		}
+		for relation := range e.relations {
+			if !slices.Contains(e.fields, relation) {
+				delete(e.relations, relation)
+			}
+		}
+	}
+
+	return e, nil
+}
+
+func (e expansion) includes(relation string) bool {
+	return e.relations[relation]
+}
+
+// render убирает из ресурса невстроенные связи и поля, не перечисленные в fields.
+func (e expansion) render(resource any, relations ...string) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(b, &object); err != nil {
+		return nil, err
+	}
+
+	for _, relation := range relations {
+		if !e.includes(relation) {
+			delete(object, relation)
+		}
+	}
+	if e.fields == nil {
+		return object, nil
+	}
+
+	selected := make(map[string]json.RawMessage, len(e.fields))
+	for _, field := range e.fields {
+		if value, ok := object[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+// splitList разбирает значение вида "a,b, c", пропуская пустые элементы.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
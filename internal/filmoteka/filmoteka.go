@@ -2,20 +2,28 @@ package filmoteka
 
 import (
 	"TestVK/internal/config"
-	"database/sql"
+	"TestVK/internal/db"
+	"TestVK/internal/jwt"
+	"TestVK/internal/rbac"
 	"log/slog"
 )
 
 type Filmoteka struct {
-	Db     *sql.DB
-	Config *config.AppConfig
-	Logger *slog.Logger
+	Store    db.Store
+	Config   *config.AppConfig
+	Logger   *slog.Logger
+	Verifier *jwt.Verifier
+	Policy   *rbac.Policy
+
+	stats *statsCache
 }
 
-func NewFilmoteka(db *sql.DB, appConfig *config.AppConfig, logger *slog.Logger) *Filmoteka {
+func NewFilmoteka(store db.Store, appConfig *config.AppConfig, logger *slog.Logger) *Filmoteka {
 	return &Filmoteka{
-		Db:     db,
+		Store:  store,
 		Config: appConfig,
 		Logger: logger,
+		Policy: rbac.Default(),
+		stats:  newStatsCache(appConfig.Stats.CacheTTL),
 	}
 }
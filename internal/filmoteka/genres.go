@@ -0,0 +1,219 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Facets - сводка по всей выборке, которая отдаётся вместе со страницей при
+// facets=genres.
+type Facets struct {
+	Genres []db.GenreFacet `json:"genres"`
+}
+
+func (f *Filmoteka) handleGetGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := f.Store.GetGenres()
+	if err != nil {
+		f.Logger.Warn("Error getting genres", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.GenresGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genres)
+	f.Logger.Info("Genres", "genres", len(genres))
+}
+
+func (f *Filmoteka) handleAddGenre(w http.ResponseWriter, r *http.Request) {
+	var genre db.Genre
+	err := json.NewDecoder(r.Body).Decode(&genre)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	genre.Name = strings.TrimSpace(genre.Name)
+	if genre.Name == "" {
+		writeFieldError(w, r, "name", i18n.GenreNameRequired)
+		return
+	}
+
+	genre, err = f.Store.AddGenre(genre)
+	if err != nil {
+		f.genreError(w, r, err, "Error creating genre", i18n.GenreAddError)
+		return
+	}
+
+	writeCreated(w, "/genres/"+strconv.Itoa(genre.ID), genre)
+	f.Logger.Info("New genre", "id", genre.ID, "name", genre.Name)
+}
+
+func (f *Filmoteka) handleGetGenre(w http.ResponseWriter, r *http.Request) {
+	genreID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get genre id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidGenreID)
+		return
+	}
+
+	genre, err := f.Store.GetGenre(genreID)
+	if err != nil {
+		f.genreError(w, r, err, "Error getting genre", i18n.GenreGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genre)
+	f.Logger.Info("Genre", "id", genre.ID)
+}
+
+func (f *Filmoteka) handleReplaceGenre(w http.ResponseWriter, r *http.Request) {
+	genreID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get genre id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidGenreID)
+		return
+	}
+
+	var genre db.Genre
+	err = json.NewDecoder(r.Body).Decode(&genre)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	genre.ID = genreID
+	genre.Name = strings.TrimSpace(genre.Name)
+	if genre.Name == "" {
+		writeFieldError(w, r, "name", i18n.GenreNameRequired)
+		return
+	}
+
+	if err := f.Store.UpdateGenre(genre); err != nil {
+		f.genreError(w, r, err, "Error updating genre", i18n.GenreUpdateError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genre)
+	f.Logger.Info("Genre update", "id", genre.ID, "name", genre.Name)
+}
+
+func (f *Filmoteka) handleDeleteGenre(w http.ResponseWriter, r *http.Request) {
+	genreID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get genre id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidGenreID)
+		return
+	}
+
+	if err := f.Store.DeleteGenre(genreID); err != nil {
+		f.genreError(w, r, err, "Error deleting genre", i18n.GenreDeleteError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.GenreDeleted)))
+	f.Logger.Info("Deleted genre", "id", genreID)
+}
+
+// genreError отвечает на ошибку хранилища при работе со справочником жанров.
+func (f *Filmoteka) genreError(w http.ResponseWriter, r *http.Request, err error, logMessage string, errorKey i18n.Key) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, r, codeNotFound, i18n.GenreNotFound)
+	case errors.Is(err, db.ErrDuplicateKey):
+		writeError(w, r, codeConflict, i18n.GenreExists)
+	case errors.Is(err, db.ErrValueTooLong):
+		writeFieldError(w, r, "name", i18n.GenreNameTooLong)
+	default:
+		f.Logger.Warn(logMessage, "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, errorKey)
+	}
+}
+
+func (f *Filmoteka) handleGetMovieGenres(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	genres, err := f.Store.GetMovieGenres(movieID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting movie genres", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieGenresGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genres)
+	f.Logger.Info("Movie genres", "movie_id", movieID, "genres", len(genres))
+}
+
+func (f *Filmoteka) handleReplaceMovieGenres(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	var genreIDs []int
+	err = json.NewDecoder(r.Body).Decode(&genreIDs)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	genres, err := f.Store.ReplaceMovieGenres(movieID, genreIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		case errors.Is(err, db.ErrForeignKeyViolation):
+			writeError(w, r, codeValidationFailed, i18n.GenreNotFound)
+		case errors.Is(err, db.ErrDuplicateKey):
+			writeError(w, r, codeValidationFailed, i18n.DuplicateGenre)
+		default:
+			f.Logger.Warn("Error replacing movie genres", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.MovieGenresUpdateError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genres)
+	f.Logger.Info("Movie genres replaced", "movie_id", movieID, "genres", len(genres))
+}
+
+// parseFacets проверяет параметр facets. Пока поддерживаются только фасеты по
+// жанрам.
+func parseFacets(r *http.Request) (bool, error) {
+	if !r.URL.Query().Has("facets") {
+		return false, nil
+	}
+	for _, facet := range splitList(r.URL.Query().Get("facets")) {
+		if facet != "genres" {
+			return false, ErrUnknownField
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPathDepth = 6
+	maxPathDepth     = 10
+)
+
+func (f *Filmoteka) handleGetCoStars(w http.ResponseWriter, r *http.Request) {
+	actorID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+	limit, err := parseRecommendationsLimit(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	coStars, err := f.Store.GetCoStars(actorID, limit)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ActorNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting co-stars", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.CoStarsGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coStars)
+	f.Logger.Info("Co-stars", "actor_id", actorID, "total", len(coStars))
+}
+
+// handleGetActorPath ищет кратчайшую цепочку фильмов между двумя актёрами.
+func (f *Filmoteka) handleGetActorPath(w http.ResponseWriter, r *http.Request) {
+	fromID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+	toID, err := strconv.Atoi(r.PathValue("other_id"))
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+
+	maxDepth := defaultPathDepth
+	if depthParam := r.URL.Query().Get("max_depth"); depthParam != "" {
+		maxDepth, err = strconv.Atoi(depthParam)
+		if err != nil || maxDepth < 1 || maxDepth > maxPathDepth {
+			writeError(w, r, codeInvalidParameter, i18n.InvalidMaxDepth, maxPathDepth)
+			return
+		}
+	}
+
+	path, err := f.Store.GetActorPath(fromID, toID, maxDepth)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ActorNotFound)
+		return
+	}
+	if errors.Is(err, db.ErrNoPath) {
+		writeError(w, r, codeNotFound, i18n.ActorPathNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error finding actor path", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorPathError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(path)
+	f.Logger.Info("Actor path", "from", fromID, "to", toID, "degrees", path.Degrees)
+}
@@ -2,217 +2,232 @@ package filmoteka
 
 import (
 	"TestVK/internal/db"
+	"TestVK/internal/i18n"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// MovieRequest - тело создания и полной замены фильма. Рейтинга в нём нет:
+// он вычисляется по отзывам пользователей.
 type MovieRequest struct {
-	Id             int     `json:"id"`
-	Title          string  `json:"title"`
-	Description    string  `json:"description"`
-	ReleaseDateStr string  `json:"release_date"`
-	Rating         float64 `json:"rating"`
+	Id             int    `json:"id"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	ReleaseDateStr string `json:"release_date"`
+}
+
+// movie переводит проверенный запрос в фильм; дата выхода к этому моменту
+// уже прошла проверку формата.
+func (req MovieRequest) movie(movieID int) db.Movie {
+	return db.Movie{
+		ID:          movieID,
+		Title:       req.Title,
+		Description: req.Description,
+		ReleaseDate: releaseDate(req),
+	}
 }
 
 func (f *Filmoteka) handleAddActor(w http.ResponseWriter, r *http.Request) {
 	var actor db.Actor
-	err := json.NewDecoder(r.Body).Decode(&actor)
-	if err != nil {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Невозможно прочитать тело запроса", http.StatusBadRequest)
+	decoded, ok := f.decodeBody(w, r, &actor, i18n.InvalidBody)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
-	if actor.Name == "" {
-		http.Error(w, "Имя актера обязательно для заполнения", http.StatusBadRequest)
+	if violations := mergeViolations(decoded, validate(actor, requiredActorRules, actorRules)); len(violations) > 0 {
+		writeViolations(w, r, violations)
 		return
 	}
 
-	if err := db.AddActor(f.Db, actor); err != nil {
-		f.Logger.Warn("Error creating actor", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при добавлении актера в базу данных", http.StatusInternalServerError)
+	actor, err := f.Store.AddActor(actor)
+	if err != nil {
+		f.Logger.Warn("Error creating actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorAddError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte("Актер успешно добавлен в базу данных"))
-	f.Logger.Info("New Actor", actor.Name)
+	writeCreated(w, "/actors/"+strconv.Itoa(actor.Id), actor)
+	f.Logger.Info("New Actor", "id", actor.Id, "name", actor.Name)
 }
 
+// handleUpdateActor - устаревший POST /actors/update с id в теле; тело
+// разбирается так же, как merge patch в PATCH /actors/{id}.
 func (f *Filmoteka) handleUpdateActor(w http.ResponseWriter, r *http.Request) {
-	var actor db.Actor
-	err := json.NewDecoder(r.Body).Decode(&actor)
-	if err != nil {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Невозможно прочитать тело запроса", http.StatusBadRequest)
+	var patchReq ActorPatchRequest
+	decoded, ok := f.decodeBody(w, r, &patchReq, i18n.InvalidBody)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
-	if err := db.UpdateActor(f.Db, actor); err != nil {
-		f.Logger.Warn("Error updating actor", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при обновлении актера", http.StatusInternalServerError)
+	if violations := mergeViolations(decoded, validate(patchReq, legacyUpdateActorRules, actorPatchRules)); len(violations) > 0 {
+		writeViolations(w, r, violations)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte("Актер успешно обновлен в базу данных"))
-	f.Logger.Info("New Actor", actor.Id, actor.Name)
+	f.updateActor(w, r, patchReq.Id.Value, patchReq)
 }
 
-func (f *Filmoteka) handleDeleteActor(w http.ResponseWriter, r *http.Request) {
-	idParam := r.URL.Query().Get("id")
-	actorID, err := strconv.Atoi(idParam)
+// handlePatchActor частично обновляет актёра по правилам merge patch:
+// отсутствующее поле не меняется, null сбрасывает его.
+func (f *Filmoteka) handlePatchActor(w http.ResponseWriter, r *http.Request) {
+	actorID, err := resourceID(r, "id")
 	if err != nil {
-		f.Logger.Info("Can't get actor id", http.StatusBadRequest, err)
-		http.Error(w, "Неверный идентификатор актера", http.StatusBadRequest)
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
 		return
 	}
 
-	if err := db.DeleteActor(f.Db, actorID); err != nil {
-		f.Logger.Warn("Error deleting actor", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при удалении актера", http.StatusInternalServerError)
+	var patchReq ActorPatchRequest
+	decoded, ok := f.decodePatch(w, r, &patchReq, i18n.InvalidBody)
+	if !ok {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Актер успешно удален из базы данных"))
-	f.Logger.Info("Deleted actor", actorID)
-}
-
-func (f *Filmoteka) handleAddMovie(w http.ResponseWriter, r *http.Request) {
-	var movieReq MovieRequest
-	err := json.NewDecoder(r.Body).Decode(&movieReq)
-	if err != nil {
-		f.Logger.Info("Ошибка при декодировании запроса", err.Error(), http.StatusBadRequest)
-		http.Error(w, "Ошибка при декодировании запроса", http.StatusBadRequest)
+	violations := mergeViolations(decoded, validate(patchReq, actorPatchRules, sameID(actorID, func(req ActorPatchRequest) int { return req.Id.Value })))
+	if len(violations) > 0 {
+		writeViolations(w, r, violations)
 		return
 	}
-	defer r.Body.Close()
 
-	if movieReq.ReleaseDateStr == "" {
-		f.Logger.Info("Дата выхода фильма обязательна для заполнения", http.StatusBadRequest)
-		http.Error(w, "Дата выхода фильма обязательна для заполнения", http.StatusBadRequest)
+	f.updateActor(w, r, actorID, patchReq)
+}
+
+func (f *Filmoteka) updateActor(w http.ResponseWriter, r *http.Request, actorID int, patchReq ActorPatchRequest) {
+	actor, err := f.Store.UpdateActor(actorID, patchReq.patch())
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ActorNotFound)
 		return
 	}
-
-	releaseDate, err := parseDate(movieReq.ReleaseDateStr)
 	if err != nil {
-		f.Logger.Info("Wrong data format", err.Error(), http.StatusBadRequest)
-		http.Error(w, "Неверный формат даты выхода фильма", http.StatusBadRequest)
+		f.Logger.Warn("Error updating actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorUpdateError)
 		return
 	}
 
-	movie := db.Movie{
-		Title:       movieReq.Title,
-		Description: movieReq.Description,
-		ReleaseDate: releaseDate,
-		Rating:      movieReq.Rating,
-	}
-	defer r.Body.Close()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actor)
+	f.Logger.Info("Actor update", "id", actor.Id, "name", actor.Name)
+}
 
-	if movie.Title == "" || movie.ReleaseDate.IsZero() {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Название и дата выхода фильма обязательны для заполнения", http.StatusBadRequest)
+func (f *Filmoteka) handleDeleteActor(w http.ResponseWriter, r *http.Request) {
+	actorID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
 		return
 	}
 
-	if err := db.AddMovie(f.Db, movie); err != nil {
-		f.Logger.Warn("Error creating movie", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при добавлении фильма в базу данных", http.StatusInternalServerError)
+	if err := f.Store.DeleteActor(actorID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, r, codeNotFound, i18n.ActorNotFound)
+			return
+		}
+		f.Logger.Warn("Error deleting actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorDeleteError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Фильм успешно добавлен в базу данных"))
-	f.Logger.Info("New Movie", movie.Title)
+	w.Write([]byte(message(r, i18n.ActorDeleted)))
+	f.Logger.Info("Deleted actor", "id", actorID)
 }
 
-func (f *Filmoteka) handleUpdateMovie(w http.ResponseWriter, r *http.Request) {
+func (f *Filmoteka) handleAddMovie(w http.ResponseWriter, r *http.Request) {
 	var movieReq MovieRequest
-	err := json.NewDecoder(r.Body).Decode(&movieReq)
-	if err != nil {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Ошибка при декодировании запроса", http.StatusBadRequest)
+	decoded, ok := f.decodeBody(w, r, &movieReq, i18n.InvalidRequestJSON)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
-	if movieReq.ReleaseDateStr == "" {
-		f.Logger.Info("Дата выхода фильма обязательна для заполнения", http.StatusBadRequest)
-		http.Error(w, "Дата выхода фильма обязательна для заполнения", http.StatusBadRequest)
+	if violations := mergeViolations(decoded, validate(movieReq, requiredMovieRules, movieRules)); len(violations) > 0 {
+		writeViolations(w, r, violations)
 		return
 	}
 
-	releaseDate, err := parseDate(movieReq.ReleaseDateStr)
+	movie := movieReq.movie(0)
+	movie.Rating = f.ratingPrior().Unrated()
+	movie, err := f.Store.AddMovie(movie)
 	if err != nil {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Неверный формат даты выхода фильма", http.StatusBadRequest)
+		f.Logger.Warn("Error creating movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieAddError)
 		return
 	}
 
-	movie := db.Movie{
-		Title:       movieReq.Title,
-		Description: movieReq.Description,
-		ReleaseDate: releaseDate,
-		Rating:      movieReq.Rating,
+	writeCreated(w, "/movies/"+strconv.Itoa(movie.ID), movie)
+	f.Logger.Info("New Movie", "id", movie.ID, "title", movie.Title)
+}
+
+// handleUpdateMovie - устаревший POST /movies/update с id в теле; тело
+// разбирается так же, как merge patch в PATCH /movies/{id}.
+func (f *Filmoteka) handleUpdateMovie(w http.ResponseWriter, r *http.Request) {
+	var patchReq MoviePatchRequest
+	decoded, ok := f.decodeBody(w, r, &patchReq, i18n.InvalidRequestJSON)
+	if !ok {
+		return
 	}
 
-	if movie.ID == 0 {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Идентификатор фильма обязателен для обновления", http.StatusBadRequest)
+	if violations := mergeViolations(decoded, validate(patchReq, legacyUpdateMovieRules, moviePatchRules)); len(violations) > 0 {
+		writeViolations(w, r, violations)
 		return
 	}
 
-	if err := db.UpdateMovie(f.Db, movie); err != nil {
-		f.Logger.Warn("Error updating movie", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при обновлении информации о фильме", http.StatusInternalServerError)
+	movie, err := f.Store.UpdateMovie(patchReq.Id.Value, patchReq.patch())
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error updating movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieUpdateError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Информация о фильме успешно обновлена"))
-	f.Logger.Info("Movie update", movie.ID, movie.Title)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movie)
+	f.Logger.Info("Movie update", "id", movie.ID, "title", movie.Title)
 }
 
 func (f *Filmoteka) handleDeleteMovie(w http.ResponseWriter, r *http.Request) {
-	idParam := r.URL.Query().Get("id")
-	movieID, err := strconv.Atoi(idParam)
+	movieID, err := resourceID(r, "id")
 	if err != nil {
 		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Неверный идентификатор фильма", http.StatusBadRequest)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
 		return
 	}
 
 	if movieID == 0 {
-		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Идентификатор фильма обязателен для обновления", http.StatusBadRequest)
+		f.Logger.Info("Response", slog.String("Body", "movie id is required"))
+		writeError(w, r, codeInvalidParameter, i18n.MovieIDRequired)
 		return
 	}
 
-	if err := db.DeleteMovie(f.Db, movieID); err != nil {
-		f.Logger.Warn("Error deleting movie", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при удалении фильма", http.StatusInternalServerError)
+	if err := f.Store.DeleteMovie(movieID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, r, codeNotFound, i18n.MovieNotFound)
+			return
+		}
+		f.Logger.Warn("Error deleting movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieDeleteError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Фильм успешно удален из базы данных"))
-	f.Logger.Info("Movie deleted", movieID)
+	w.Write([]byte(message(r, i18n.MovieDeleted)))
+	f.Logger.Info("Movie deleted", "id", movieID)
 }
 
 func (f *Filmoteka) handleUpdateMovieActors(w http.ResponseWriter, r *http.Request) {
-	movieIDParam := r.URL.Query().Get("movie_id")
-	movieID, err := strconv.Atoi(movieIDParam)
+	movieID, err := resourceID(r, "movie_id")
 	if err != nil {
 		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Неверный идентификатор фильма", http.StatusBadRequest)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
 		return
 	}
 
@@ -220,40 +235,67 @@ func (f *Filmoteka) handleUpdateMovieActors(w http.ResponseWriter, r *http.Reque
 	err = json.NewDecoder(r.Body).Decode(&actorID)
 	if err != nil {
 		f.Logger.Info("Response", slog.String("Body", err.Error()))
-		http.Error(w, "Невозможно прочитать тело запроса", http.StatusBadRequest)
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
 		return
 	}
 	defer r.Body.Close()
 
-	if err := db.AddMovieActor(f.Db, movieID, actorID); err != nil {
-		f.Logger.Warn("Error adding actor to movie", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при обновлении списка актеров для фильма", http.StatusInternalServerError)
+	if err := f.Store.AddMovieActor(movieID, actorID); err != nil {
+		switch {
+		case errors.Is(err, db.ErrForeignKeyViolation):
+			writeError(w, r, codeNotFound, i18n.MovieOrActorNotFound)
+		case errors.Is(err, db.ErrDuplicateKey):
+			writeError(w, r, codeConflict, i18n.ActorAlreadyInCast)
+		default:
+			f.Logger.Warn("Error adding actor to movie", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.CastUpdateError)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Список актеров для фильма успешно обновлен"))
-	f.Logger.Info("Movie actors update", movieID, actorID)
+	w.Write([]byte(message(r, i18n.CastUpdated)))
+	f.Logger.Info("Movie actors update", "movie_id", movieID, "actor_id", actorID)
 }
 
 func (f *Filmoteka) handleSearchMoviesByActorName(w http.ResponseWriter, r *http.Request) {
 	actorName := r.URL.Query().Get("actor_name")
 	if actorName == "" {
 		f.Logger.Info("Response", slog.String("Body", actorName))
-		http.Error(w, "Не указано имя актера", http.StatusBadRequest)
+		writeError(w, r, codeInvalidParameter, i18n.ActorNameQueryRequired)
 		return
 	}
 
-	movies, err := db.SearchMoviesByActorName(f.Db, actorName)
+	page, err := f.parsePage(r)
 	if err != nil {
-		f.Logger.Warn("Error searching movie", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при поиске фильмов по имени актера", http.StatusInternalServerError)
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movies)
-	f.Logger.Info("Actors movies", movies)
+	fuzzy := fuzzySearch(r)
+	var movies []db.Movie
+	var info db.PageInfo
+	if fuzzy {
+		movies, info, err = f.Store.SearchMoviesFuzzy("", actorName, f.similarityThreshold(), page)
+	} else {
+		movies, info, err = f.Store.SearchMoviesByActorName(actorName, page)
+	}
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MoviesByActorError)
+		return
+	}
+
+	if info.Total == 0 && !fuzzy {
+		f.suggest(w, f.Store.SuggestActors, actorName)
+	}
+	writePage(w, r, page, info, movies)
+	f.Logger.Info("Actors movies", "movies", movies)
 }
 
 func (f *Filmoteka) handleGetMovies(w http.ResponseWriter, r *http.Request) {
@@ -277,71 +319,545 @@ func (f *Filmoteka) handleGetMovies(w http.ResponseWriter, r *http.Request) {
 		sortOrder = "desc"
 	}
 
-	movies, err := db.GetMoviesWithSorting(f.Db, orderBy, sortOrder)
+	filter, err := parseMovieFilter(queryValues)
 	if err != nil {
-		f.Logger.Warn("Error searching movies", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при получении списка фильмов", http.StatusInternalServerError)
+		f.Logger.Info("Invalid filter", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidFilter)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movies)
-	f.Logger.Info("Movies", movies)
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	withFacets, err := parseFacets(r)
+	if err != nil {
+		f.Logger.Info("Invalid facets", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidFilter)
+		return
+	}
+
+	movies, info, err := f.Store.GetMoviesWithSorting(filter, orderBy, sortOrder, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movies", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MoviesGetError)
+		return
+	}
+
+	if withFacets {
+		genres, err := f.Store.GetMovieGenreFacets(filter)
+		if err != nil {
+			f.Logger.Warn("Error counting genre facets", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.MoviesGetError)
+			return
+		}
+		writeFacetedPage(w, r, page, info, movies, Facets{Genres: genres})
+		f.Logger.Info("Movies", "movies", movies)
+		return
+	}
+
+	writePage(w, r, page, info, movies)
+	f.Logger.Info("Movies", "movies", movies)
 }
 
 func (f *Filmoteka) handleSearchMoviesByTitleOrActor(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("q") {
+		f.handleSearchMovies(w, r)
+		return
+	}
+	if r.URL.Query().Has("director") || r.URL.Query().Has("crew_name") {
+		f.handleSearchMoviesByCrew(w, r)
+		return
+	}
+
 	titleFragment := r.URL.Query().Get("title_fragment")
 	actorNameFragment := r.URL.Query().Get("actor_name_fragment")
 
 	if titleFragment == "" && actorNameFragment == "" {
 		f.Logger.Info("Response", slog.String("Body", titleFragment))
-		http.Error(w, "Не указан фрагмент названия или фрагмент имени актёра", http.StatusBadRequest)
+		writeError(w, r, codeInvalidParameter, i18n.TitleOrActorRequired)
 		return
 	}
 
-	movies, err := db.SearchMoviesByTitleOrActorName(f.Db, titleFragment, actorNameFragment)
+	page, err := f.parsePage(r)
 	if err != nil {
-		f.Logger.Warn("Error searching movie by title or actor", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при поиске фильмов", http.StatusInternalServerError)
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movies)
-	f.Logger.Info("Movies by title or actor", movies)
+	fuzzy := fuzzySearch(r)
+	var movies []db.Movie
+	var info db.PageInfo
+	if fuzzy {
+		movies, info, err = f.Store.SearchMoviesFuzzy(titleFragment, actorNameFragment, f.similarityThreshold(), page)
+	} else {
+		movies, info, err = f.Store.SearchMoviesByTitleOrActorName(titleFragment, actorNameFragment, page)
+	}
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movie by title or actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MoviesSearchError)
+		return
+	}
+
+	if info.Total == 0 && !fuzzy {
+		if titleFragment != "" {
+			f.suggest(w, f.Store.SuggestMovies, titleFragment)
+		}
+		if actorNameFragment != "" {
+			f.suggest(w, f.Store.SuggestActors, actorNameFragment)
+		}
+	}
+	writePage(w, r, page, info, movies)
+	f.Logger.Info("Movies by title or actor", "movies", movies)
 }
 
 func (f *Filmoteka) handleGetActors(w http.ResponseWriter, r *http.Request) {
-	actors, err := db.GetActors(f.Db)
+	page, err := f.parsePage(r)
 	if err != nil {
-		f.Logger.Warn("Error getting actors", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при получении списка актёров", http.StatusInternalServerError)
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(actors)
-	f.Logger.Info("actors", actors)
+	actors, info, err := f.Store.GetActors(page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting actors", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorsGetError)
+		return
+	}
+
+	writePage(w, r, page, info, actors)
+	f.Logger.Info("actors", "actors", actors)
 }
 
 func (f *Filmoteka) handleGetActorMovies(w http.ResponseWriter, r *http.Request) {
 	actorName := r.URL.Query().Get("actor_name")
 	if actorName == "" {
 		f.Logger.Info("Response", slog.String("Body", actorName))
-		http.Error(w, "Не указано имя актёра", http.StatusBadRequest)
+		writeError(w, r, codeInvalidParameter, i18n.ActorNameQueryRequired)
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	fuzzy := fuzzySearch(r)
+	var movies []db.MovieCredit
+	var info db.PageInfo
+	if fuzzy {
+		// Нечёткий поиск может найти нескольких актёров, поэтому роли не возвращаются.
+		var found []db.Movie
+		found, info, err = f.Store.SearchMoviesFuzzy("", actorName, f.similarityThreshold(), page)
+		movies = movieCredits(found)
+	} else {
+		movies, info, err = f.Store.GetMoviesByActorName(actorName, page)
+	}
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movie by actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MoviesByActorError)
+		return
+	}
+
+	if info.Total == 0 && !fuzzy {
+		f.suggest(w, f.Store.SuggestActors, actorName)
+	}
+	writePage(w, r, page, info, movies)
+	f.Logger.Info("movies by actor", "actor_name", actorName, "movies", movies)
+}
+
+func (f *Filmoteka) handleGetMovie(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	expand, err := parseExpansion(r, movieFields, "cast", "crew", "genres", "ratings")
+	if err != nil {
+		f.Logger.Info("Invalid expansion", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidExpand)
+		return
+	}
+
+	movie, err := f.Store.GetMovie(movieID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieGetError)
+		return
+	}
+
+	detail := MovieDetail{Movie: movie}
+	if expand.includes("cast") {
+		detail.Cast, err = f.Store.GetMovieCast(movieID)
+		if err != nil {
+			f.Logger.Warn("Error getting movie cast", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.CastGetError)
+			return
+		}
+	}
+	if expand.includes("crew") {
+		detail.Crew, err = f.Store.GetMovieCrew(movieID)
+		if err != nil {
+			f.Logger.Warn("Error getting movie crew", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.CrewGetError)
+			return
+		}
+	}
+	if expand.includes("genres") {
+		detail.Genres, err = f.Store.GetMovieGenres(movieID)
+		if err != nil {
+			f.Logger.Warn("Error getting movie genres", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.MovieGenresGetError)
+			return
+		}
+	}
+	if expand.includes("ratings") {
+		detail.Ratings, err = f.Store.GetMovieRatingStats(movieID)
+		if err != nil {
+			f.Logger.Warn("Error getting movie rating stats", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.RatingsGetError)
+			return
+		}
+	}
+
+	response, err := expand.render(detail, "cast", "crew", "genres", "ratings")
+	if err != nil {
+		f.Logger.Warn("Error rendering movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	f.Logger.Info("Movie", "id", movie.ID, "cast", len(detail.Cast))
+}
+
+func (f *Filmoteka) handleReplaceMovie(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	var movieReq MovieRequest
+	decoded, ok := f.decodeBody(w, r, &movieReq, i18n.InvalidRequestJSON)
+	if !ok {
+		return
+	}
+
+	violations := mergeViolations(decoded, validate(movieReq, requiredMovieRules, movieRules, sameID(movieID, func(req MovieRequest) int { return req.Id })))
+	if len(violations) > 0 {
+		writeViolations(w, r, violations)
+		return
+	}
+
+	movie, err := f.Store.ReplaceMovie(movieReq.movie(movieID))
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error replacing movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieUpdateError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movie)
+	f.Logger.Info("Movie replace", "id", movie.ID, "title", movie.Title)
+}
+
+// handlePatchMovie частично обновляет фильм по правилам merge patch:
+// отсутствующее поле не меняется, null сбрасывает его.
+func (f *Filmoteka) handlePatchMovie(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	var patchReq MoviePatchRequest
+	decoded, ok := f.decodePatch(w, r, &patchReq, i18n.InvalidRequestJSON)
+	if !ok {
+		return
+	}
+
+	violations := mergeViolations(decoded, validate(patchReq, moviePatchRules, sameID(movieID, func(req MoviePatchRequest) int { return req.Id.Value })))
+	if len(violations) > 0 {
+		writeViolations(w, r, violations)
 		return
 	}
 
-	movies, err := db.GetMoviesByActorName(f.Db, actorName)
+	movie, err := f.Store.UpdateMovie(movieID, patchReq.patch())
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
 	if err != nil {
-		f.Logger.Warn("Error searching movie by actor", http.StatusInternalServerError, err)
-		http.Error(w, "Ошибка при получении списка фильмов по имени актёра", http.StatusInternalServerError)
+		f.Logger.Warn("Error updating movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MovieUpdateError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movies)
-	f.Logger.Info("movies by actor", actorName, movies)
+	json.NewEncoder(w).Encode(movie)
+	f.Logger.Info("Movie update", "id", movie.ID, "title", movie.Title)
+}
+
+func (f *Filmoteka) handleGetActor(w http.ResponseWriter, r *http.Request) {
+	actorID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+
+	expand, err := parseExpansion(r, actorFields, "movies", "crew")
+	if err != nil {
+		f.Logger.Info("Invalid expansion", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidExpand)
+		return
+	}
+
+	actor, err := f.Store.GetActor(actorID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ActorNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorGetError)
+		return
+	}
+
+	detail := ActorDetail{Actor: actor}
+	if expand.includes("movies") {
+		detail.Movies, err = f.Store.GetActorFilmography(actorID)
+		if err != nil {
+			f.Logger.Warn("Error getting filmography", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.ActorMoviesGetError)
+			return
+		}
+	}
+	if expand.includes("crew") {
+		detail.Crew, err = f.Store.GetPersonCrewCredits(actorID)
+		if err != nil {
+			f.Logger.Warn("Error getting crew credits", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.ActorMoviesGetError)
+			return
+		}
+	}
+
+	response, err := expand.render(detail, "movies", "crew")
+	if err != nil {
+		f.Logger.Warn("Error rendering actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	f.Logger.Info("Actor", "id", actor.Id, "movies", len(detail.Movies))
+}
+
+func (f *Filmoteka) handleReplaceActor(w http.ResponseWriter, r *http.Request) {
+	actorID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+
+	var actor db.Actor
+	decoded, ok := f.decodeBody(w, r, &actor, i18n.InvalidBody)
+	if !ok {
+		return
+	}
+
+	violations := mergeViolations(decoded, validate(actor, requiredActorRules, actorRules, sameID(actorID, func(actor db.Actor) int { return actor.Id })))
+	if len(violations) > 0 {
+		writeViolations(w, r, violations)
+		return
+	}
+	actor.Id = actorID
+
+	actor, err = f.Store.ReplaceActor(actor)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ActorNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error replacing actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorUpdateError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actor)
+	f.Logger.Info("Actor replace", "id", actor.Id, "name", actor.Name)
+}
+
+func (f *Filmoteka) handleGetActorMoviesByID(w http.ResponseWriter, r *http.Request) {
+	actorID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get actor id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidActorID)
+		return
+	}
+
+	if _, err := f.Store.GetActor(actorID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, r, codeNotFound, i18n.ActorNotFound)
+			return
+		}
+		f.Logger.Warn("Error getting actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorMoviesGetError)
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	movies, info, err := f.Store.GetMoviesByActorID(actorID, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movie by actor", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorMoviesGetError)
+		return
+	}
+
+	writePage(w, r, page, info, movies)
+	f.Logger.Info("movies by actor", "actor_id", actorID, "movies", movies)
+}
+
+// resourceID берёт идентификатор из пути ({id}) или, для старых маршрутов, из
+// query-параметра legacyParam.
+func resourceID(r *http.Request, legacyParam string) (int, error) {
+	idParam := r.PathValue("id")
+	if idParam == "" {
+		idParam = r.URL.Query().Get(legacyParam)
+	}
+	return strconv.Atoi(idParam)
+}
+
+// writeCreated отвечает 201 с созданным ресурсом в теле и его адресом в
+// заголовке Location.
+func writeCreated(w http.ResponseWriter, location string, resource interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resource)
+}
+
+// parseMovieFilter читает фильтры списка фильмов. Актёров можно перечислить
+// через запятую или повторить параметр actor_id.
+func parseMovieFilter(queryValues url.Values) (db.MovieFilter, error) {
+	filter := db.MovieFilter{
+		TitlePrefix: queryValues.Get("title_prefix"),
+		Description: queryValues.Get("description"),
+	}
+
+	var err error
+	if from := queryValues.Get("release_date_from"); from != "" {
+		if filter.ReleasedFrom, err = parseDate(from); err != nil {
+			return db.MovieFilter{}, fmt.Errorf("release_date_from: %w", err)
+		}
+	}
+	if to := queryValues.Get("release_date_to"); to != "" {
+		if filter.ReleasedTo, err = parseDate(to); err != nil {
+			return db.MovieFilter{}, fmt.Errorf("release_date_to: %w", err)
+		}
+	}
+
+	if filter.MinRating, err = parseRating(queryValues.Get("rating_min")); err != nil {
+		return db.MovieFilter{}, fmt.Errorf("rating_min: %w", err)
+	}
+	if filter.MaxRating, err = parseRating(queryValues.Get("rating_max")); err != nil {
+		return db.MovieFilter{}, fmt.Errorf("rating_max: %w", err)
+	}
+
+	for _, param := range queryValues["actor_id"] {
+		for _, idParam := range strings.Split(param, ",") {
+			actorID, err := strconv.Atoi(strings.TrimSpace(idParam))
+			if err != nil {
+				return db.MovieFilter{}, fmt.Errorf("actor_id: %w", err)
+			}
+			filter.ActorIDs = append(filter.ActorIDs, actorID)
+		}
+	}
+
+	seen := make(map[int]struct{})
+	for _, param := range queryValues["genre_id"] {
+		for _, idParam := range strings.Split(param, ",") {
+			genreID, err := strconv.Atoi(strings.TrimSpace(idParam))
+			if err != nil {
+				return db.MovieFilter{}, fmt.Errorf("genre_id: %w", err)
+			}
+			if _, ok := seen[genreID]; !ok {
+				seen[genreID] = struct{}{}
+				filter.GenreIDs = append(filter.GenreIDs, genreID)
+			}
+		}
+	}
+
+	switch queryValues.Get("genre_match") {
+	case "", "any":
+	case "all":
+		filter.AllGenres = true
+	default:
+		return db.MovieFilter{}, fmt.Errorf("genre_match: unknown mode %q", queryValues.Get("genre_match"))
+	}
+
+	return filter, nil
+}
+
+func parseRating(ratingParam string) (*float64, error) {
+	if ratingParam == "" {
+		return nil, nil
+	}
+	rating, err := strconv.ParseFloat(ratingParam, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
 }
 
 func parseDate(dateString string) (time.Time, error) {
@@ -0,0 +1,466 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const slugBytes = 5
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// WatchedRequest - тело POST /me/watched.
+type WatchedRequest struct {
+	MovieID      int    `json:"movie_id"`
+	WatchedOnStr string `json:"watched_on"`
+	Score        *int   `json:"score"`
+}
+
+// MovieListRequest - тело POST /me/lists и PUT /me/lists/{id}. Без slug
+// новому списку выдаётся случайный, а при замене сохраняется прежний.
+type MovieListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Slug        string `json:"slug"`
+	Public      bool   `json:"public"`
+}
+
+func (f *Filmoteka) handleGetWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	items, info, err := f.Store.GetWatchlist(user.Id, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting watchlist", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.WatchlistGetError)
+		return
+	}
+
+	writePage(w, r, page, info, items)
+	f.Logger.Info("Watchlist", "user_id", user.Id, "total", info.Total)
+}
+
+func (f *Filmoteka) handleAddToWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	movieID, err := strconv.Atoi(r.PathValue("movie_id"))
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	added, err := f.Store.AddToWatchlist(user.Id, movieID)
+	if errors.Is(err, db.ErrForeignKeyViolation) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error adding to watchlist", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.WatchlistAddError)
+		return
+	}
+
+	if added {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(message(r, i18n.WatchlistAdded)))
+	} else {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(message(r, i18n.WatchlistExists)))
+	}
+	f.Logger.Info("Movie added to watchlist", "user_id", user.Id, "movie_id", movieID, "added", added)
+}
+
+func (f *Filmoteka) handleRemoveFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	movieID, err := strconv.Atoi(r.PathValue("movie_id"))
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	err = f.Store.RemoveFromWatchlist(user.Id, movieID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.WatchlistItemNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error removing from watchlist", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.WatchlistRemoveError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.WatchlistRemoved)))
+	f.Logger.Info("Movie removed from watchlist", "user_id", user.Id, "movie_id", movieID)
+}
+
+func (f *Filmoteka) handleGetWatched(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	entries, info, err := f.Store.GetWatched(user.Id, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting watched movies", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.WatchedGetError)
+		return
+	}
+
+	writePage(w, r, page, info, entries)
+	f.Logger.Info("Watched movies", "user_id", user.Id, "total", info.Total)
+}
+
+func (f *Filmoteka) handleAddWatched(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	var watchedReq WatchedRequest
+	err := json.NewDecoder(r.Body).Decode(&watchedReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	entry := db.WatchedEntry{
+		UserID:    user.Id,
+		Movie:     db.Movie{ID: watchedReq.MovieID},
+		WatchedOn: time.Now(),
+		Score:     watchedReq.Score,
+	}
+	if watchedReq.WatchedOnStr != "" {
+		entry.WatchedOn, err = parseDate(watchedReq.WatchedOnStr)
+		if err != nil {
+			f.Logger.Info("Can't parse date", "status", http.StatusBadRequest, "error", err)
+			writeFieldError(w, r, "watched_on", i18n.InvalidWatchedOn)
+			return
+		}
+	}
+
+	entry, err = f.Store.AddWatched(entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrForeignKeyViolation):
+			writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		case errors.Is(err, db.ErrInvalidScore):
+			writeFieldError(w, r, "score", i18n.InvalidScore)
+		default:
+			f.Logger.Warn("Error adding watched movie", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.WatchedAddError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+	f.Logger.Info("Movie watched", "id", entry.ID, "user_id", user.Id, "movie_id", entry.Movie.ID)
+}
+
+func (f *Filmoteka) handleDeleteWatched(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	entryID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get entry id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidWatchedID)
+		return
+	}
+
+	err = f.Store.DeleteWatched(user.Id, entryID)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.WatchedNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error deleting watched movie", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.WatchedDeleteError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.WatchedDeleted)))
+	f.Logger.Info("Watched entry deleted", "id", entryID, "user_id", user.Id)
+}
+
+func (f *Filmoteka) handleGetLists(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	lists, err := f.Store.GetLists(user.Id)
+	if err != nil {
+		f.Logger.Warn("Error getting lists", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ListsGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lists)
+	f.Logger.Info("Lists", "user_id", user.Id, "lists", len(lists))
+}
+
+func (f *Filmoteka) handleAddList(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+
+	list, ok := f.readMovieList(w, r)
+	if !ok {
+		return
+	}
+	list.UserID = user.Id
+	if list.Slug == "" {
+		slug, err := newSlug()
+		if err != nil {
+			f.Logger.Warn("Error generating slug", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.ListCreateError)
+			return
+		}
+		list.Slug = slug
+	}
+
+	detail, err := f.Store.AddList(list)
+	if err != nil {
+		f.listError(w, r, err, "Error creating list", i18n.ListCreateError)
+		return
+	}
+
+	writeCreated(w, "/me/lists/"+strconv.Itoa(detail.ID), detail)
+	f.Logger.Info("New list", "id", detail.ID, "user_id", user.Id)
+}
+
+func (f *Filmoteka) handleGetList(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	listID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get list id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidListID)
+		return
+	}
+
+	detail, err := f.Store.GetList(user.Id, listID)
+	if err != nil {
+		f.listError(w, r, err, "Error getting list", i18n.ListGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+func (f *Filmoteka) handleReplaceList(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	listID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get list id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidListID)
+		return
+	}
+
+	list, ok := f.readMovieList(w, r)
+	if !ok {
+		return
+	}
+	list.ID, list.UserID = listID, user.Id
+	if list.Slug == "" {
+		current, err := f.Store.GetList(user.Id, listID)
+		if err != nil {
+			f.listError(w, r, err, "Error getting list", i18n.ListUpdateError)
+			return
+		}
+		list.Slug = current.Slug
+	}
+
+	detail, err := f.Store.UpdateList(list)
+	if err != nil {
+		f.listError(w, r, err, "Error updating list", i18n.ListUpdateError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+	f.Logger.Info("List updated", "id", listID, "user_id", user.Id)
+}
+
+func (f *Filmoteka) handleDeleteList(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	listID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get list id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidListID)
+		return
+	}
+
+	if err := f.Store.DeleteList(user.Id, listID); err != nil {
+		f.listError(w, r, err, "Error deleting list", i18n.ListDeleteError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.ListDeleted)))
+	f.Logger.Info("List deleted", "id", listID, "user_id", user.Id)
+}
+
+func (f *Filmoteka) handleAddListMovie(w http.ResponseWriter, r *http.Request) {
+	f.changeListMovies(w, r, f.Store.AddListMovie, "Error adding movie to list", i18n.ListMovieAddError)
+}
+
+func (f *Filmoteka) handleRemoveListMovie(w http.ResponseWriter, r *http.Request) {
+	f.changeListMovies(w, r, f.Store.RemoveListMovie, "Error removing movie from list", i18n.ListMovieRemoveError)
+}
+
+// changeListMovies обслуживает PUT и DELETE /me/lists/{id}/movies/{movie_id}.
+func (f *Filmoteka) changeListMovies(w http.ResponseWriter, r *http.Request,
+	change func(userID, listID, movieID int) (db.MovieListDetail, error), logMessage string, errorKey i18n.Key) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	listID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get list id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidListID)
+		return
+	}
+	movieID, err := strconv.Atoi(r.PathValue("movie_id"))
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	detail, err := change(user.Id, listID, movieID)
+	if err != nil {
+		f.listError(w, r, err, logMessage, errorKey)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+	f.Logger.Info("List movies changed", "id", listID, "movie_id", movieID, "method", r.Method)
+}
+
+// handleGetPublicList отдаёт публичный список по slug без авторизации.
+func (f *Filmoteka) handleGetPublicList(w http.ResponseWriter, r *http.Request) {
+	detail, err := f.Store.GetPublicList(r.PathValue("slug"))
+	if err != nil {
+		f.listError(w, r, err, "Error getting public list", i18n.ListGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// readMovieList читает и проверяет тело запроса со списком.
+func (f *Filmoteka) readMovieList(w http.ResponseWriter, r *http.Request) (db.MovieList, bool) {
+	var listReq MovieListRequest
+	err := json.NewDecoder(r.Body).Decode(&listReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return db.MovieList{}, false
+	}
+	defer r.Body.Close()
+
+	list := db.MovieList{
+		Name:        strings.TrimSpace(listReq.Name),
+		Description: listReq.Description,
+		Slug:        listReq.Slug,
+		Public:      listReq.Public,
+	}
+	if list.Name == "" {
+		writeFieldError(w, r, "name", i18n.ListNameRequired)
+		return db.MovieList{}, false
+	}
+	if list.Slug != "" && (len(list.Slug) < 3 || !slugPattern.MatchString(list.Slug)) {
+		writeFieldError(w, r, "slug", i18n.InvalidSlug)
+		return db.MovieList{}, false
+	}
+
+	return list, true
+}
+
+func (f *Filmoteka) listError(w http.ResponseWriter, r *http.Request, err error, logMessage string, errorKey i18n.Key) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, r, codeNotFound, i18n.ListNotFound)
+	case errors.Is(err, db.ErrForeignKeyViolation):
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+	case errors.Is(err, db.ErrDuplicateKey):
+		writeError(w, r, codeConflict, i18n.ListSlugExists)
+	case errors.Is(err, db.ErrValueTooLong):
+		writeError(w, r, codeValidationFailed, i18n.ListColumnsTooLong)
+	default:
+		f.Logger.Warn(logMessage, "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, errorKey)
+	}
+}
+
+// newSlug возвращает случайный slug для ссылки на список.
+func newSlug() (string, error) {
+	b := make([]byte, slugBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
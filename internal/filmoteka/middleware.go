@@ -1,48 +1,242 @@
 package filmoteka
 
 import (
+	"TestVK/internal/i18n"
+	"TestVK/internal/rbac"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"strconv"
 )
 
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDBytes  = 8
+)
+
+// requestIDPattern ограничивает идентификатор, принятый от клиента: он
+// попадает в заголовки и журнал как есть.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
 var (
-	accessiblePathsForRegularUser = map[string]struct{}{
-		"/movies":                 {},
-		"/movies/search":          {},
-		"/movies/search_by_actor": {},
-		"/actors":                 {},
-		"/actors/movies":          {},
+	// routePermissions сопоставляет шаблонам маршрутов из Routes права доступа.
+	// Маршрут, которого нет в таблице, требует права rbac.All.
+	routePermissions = map[string]rbac.Permission{
+		"GET /movies":                           rbac.MoviesRead,
+		"POST /movies":                          rbac.MoviesWrite,
+		"GET /movies/search":                    rbac.MoviesRead,
+		"GET /movies/autocomplete":              rbac.MoviesRead,
+		"GET /movies/{id}":                      rbac.MoviesRead,
+		"PUT /movies/{id}":                      rbac.MoviesWrite,
+		"PATCH /movies/{id}":                    rbac.MoviesWrite,
+		"DELETE /movies/{id}":                   rbac.MoviesDelete,
+		"GET /movies/{id}/cast":                 rbac.MoviesRead,
+		"POST /movies/{id}/actors":              rbac.MoviesWrite,
+		"PUT /movies/{id}/actors":               rbac.MoviesWrite,
+		"DELETE /movies/{id}/actors/{actor_id}": rbac.MoviesWrite,
+		"GET /movies/{id}/crew":                 rbac.MoviesRead,
+		"PUT /movies/{id}/crew":                 rbac.MoviesWrite,
+		"DELETE /movies/{id}/crew/{person_id}":  rbac.MoviesWrite,
+		"GET /movies/{id}/genres":               rbac.MoviesRead,
+		"PUT /movies/{id}/genres":               rbac.MoviesWrite,
+		"GET /movies/{id}/reviews":              rbac.MoviesRead,
+		"GET /movies/{id}/reviews/me":           rbac.ReviewsWrite,
+		"PUT /movies/{id}/reviews/me":           rbac.ReviewsWrite,
+		"DELETE /movies/{id}/reviews/me":        rbac.ReviewsWrite,
+		"GET /movies/{id}/similar":              rbac.MoviesRead,
+
+		"GET /actors":                      rbac.ActorsRead,
+		"POST /actors":                     rbac.ActorsWrite,
+		"GET /actors/search":               rbac.ActorsRead,
+		"GET /actors/autocomplete":         rbac.ActorsRead,
+		"GET /actors/{id}":                 rbac.ActorsRead,
+		"PUT /actors/{id}":                 rbac.ActorsWrite,
+		"PATCH /actors/{id}":               rbac.ActorsWrite,
+		"DELETE /actors/{id}":              rbac.ActorsDelete,
+		"GET /actors/{id}/movies":          rbac.MoviesRead,
+		"GET /actors/{id}/costars":         rbac.ActorsRead,
+		"GET /actors/{id}/path/{other_id}": rbac.ActorsRead,
+
+		"GET /stats/movies":      rbac.MoviesRead,
+		"GET /stats/genres":      rbac.MoviesRead,
+		"GET /stats/actors":      rbac.MoviesRead,
+		"GET /stats/actors/ages": rbac.MoviesRead,
+		"GET /stats/ratings":     rbac.MoviesRead,
+
+		"GET /genres":         rbac.MoviesRead,
+		"POST /genres":        rbac.MoviesWrite,
+		"GET /genres/{id}":    rbac.MoviesRead,
+		"PUT /genres/{id}":    rbac.MoviesWrite,
+		"DELETE /genres/{id}": rbac.MoviesDelete,
+
+		"PATCH /reviews/{id}":  rbac.ReviewsModerate,
+		"DELETE /reviews/{id}": rbac.ReviewsModerate,
+
+		// Личные списки: каждый пользователь работает только со своими записями.
+		"GET /me/watchlist":                       rbac.Authenticated,
+		"PUT /me/watchlist/{movie_id}":            rbac.Authenticated,
+		"DELETE /me/watchlist/{movie_id}":         rbac.Authenticated,
+		"GET /me/watched":                         rbac.Authenticated,
+		"POST /me/watched":                        rbac.Authenticated,
+		"DELETE /me/watched/{id}":                 rbac.Authenticated,
+		"GET /me/lists":                           rbac.Authenticated,
+		"POST /me/lists":                          rbac.Authenticated,
+		"GET /me/lists/{id}":                      rbac.Authenticated,
+		"PUT /me/lists/{id}":                      rbac.Authenticated,
+		"DELETE /me/lists/{id}":                   rbac.Authenticated,
+		"PUT /me/lists/{id}/movies/{movie_id}":    rbac.Authenticated,
+		"DELETE /me/lists/{id}/movies/{movie_id}": rbac.Authenticated,
+		"GET /me/recommendations":                 rbac.Authenticated,
+
+		"POST /recommendations/rebuild": rbac.All,
+
+		"POST /auth/logout":         rbac.Authenticated,
+		"POST /auth/permissions":    rbac.PermissionsRead,
+		"POST /users/role":          rbac.UsersManage,
+		"POST /users/revoke_tokens": rbac.UsersManage,
+
+		"POST /actors/add":            rbac.ActorsWrite,
+		"POST /actors/update":         rbac.ActorsWrite,
+		"DELETE /actors/delete":       rbac.ActorsDelete,
+		"POST /movies/add":            rbac.MoviesWrite,
+		"POST /movies/update":         rbac.MoviesWrite,
+		"POST /movies/update_actors":  rbac.MoviesWrite,
+		"DELETE /movies/delete":       rbac.MoviesDelete,
+		"GET /movies/search_by_actor": rbac.MoviesRead,
+		"GET /actors/movies":          rbac.MoviesRead,
 	}
 )
 
+// RequestID берёт идентификатор запроса из заголовка X-Request-ID или создаёт
+// новый и возвращает его в том же заголовке ответа. Идентификатор попадает в
+// тело ответов с ошибкой.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			b := make([]byte, requestIDBytes)
+			rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RouteErrors отвечает problem+json вместо текстовых 404 и 405, которыми
+// mux отвечает на запрос, не подошедший ни к одному шаблону маршрута.
+func RouteErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Ответ mux нужен только ради статуса и заголовка Allow.
+		recorder := &statusRecorder{header: make(http.Header)}
+		handler.ServeHTTP(recorder, r)
+		if recorder.status == http.StatusMethodNotAllowed {
+			allow := recorder.header.Get("Allow")
+			w.Header().Set("Allow", allow)
+			writeError(w, r, codeMethodNotAllowed, i18n.MethodNotAllowed, r.Method, allow)
+			return
+		}
+		writeError(w, r, codeNotFound, i18n.RouteNotFound)
+	})
+}
+
+// statusRecorder запоминает статус и заголовки ответа и отбрасывает тело.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header         { return s.header }
+func (s *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (s *statusRecorder) WriteHeader(status int)      { s.status = status }
+
+// RequestIDFromContext возвращает идентификатор, назначенный RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// Localize выбирает язык сообщений по заголовку Accept-Language. Если ни один
+// из поддерживаемых языков не подходит, используется i18n.default_language
+// из конфигурации.
+func (f *Filmoteka) Localize(next http.Handler) http.Handler {
+	fallback := f.Config.I18n.DefaultLanguage
+	if !i18n.Supported(fallback) {
+		if fallback != "" {
+			f.Logger.Warn("Unsupported default language", "language", fallback, "fallback", i18n.DefaultLanguage)
+		}
+		fallback = i18n.DefaultLanguage
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := i18n.Negotiate(r.Header.Get("Accept-Language"), fallback)
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		ctx := context.WithValue(r.Context(), languageContextKey, lang)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// message возвращает сообщение на языке, выбранном Localize.
+func message(r *http.Request, key i18n.Key, args ...interface{}) string {
+	lang, ok := r.Context().Value(languageContextKey).(string)
+	if !ok {
+		lang = i18n.DefaultLanguage
+	}
+	return i18n.Message(lang, key, args...)
+}
+
 func (f *Filmoteka) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		token := r.Header.Get("Authorization")
+		token := bearerToken(r)
 		if token == "" {
 			f.Logger.Info("Response", slog.String("Unauthorized", strconv.Itoa(http.StatusUnauthorized)))
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, codeUnauthorized, i18n.TokenMissing)
 			return
 		}
 
-		role := f.GetUserRole(token)
+		user, err := f.Authenticate(token)
+		if errors.Is(err, ErrUnauthenticated) {
+			f.Logger.Info("Response", slog.String("Unauthorized", strconv.Itoa(http.StatusUnauthorized)))
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, r, codeUnauthorized, i18n.TokenInvalid)
+			return
+		}
+		if err != nil {
+			f.Logger.Warn("Error authenticating token", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.TokenCheckError)
+			return
+		}
 
-		if role != "admin" {
-			if _, ok := accessiblePathsForRegularUser[r.URL.Path]; !ok {
-				f.Logger.Info("Response", slog.String("Forbidden", strconv.Itoa(http.StatusForbidden)))
-				http.Error(w, "Forbidden", http.StatusForbidden)
-				return
-			}
+		if !f.Policy.Allowed(user.Role, requiredPermission(r)) {
+			f.Logger.Info("Response", slog.String("Forbidden", strconv.Itoa(http.StatusForbidden)),
+				"role", user.Role, "method", r.Method, "path", r.URL.Path)
+			writeError(w, r, codeForbidden, i18n.PermissionDenied)
+			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (f *Filmoteka) GetUserRole(token string) string {
-	if token == f.Config.Auth.Admin {
-		return "admin"
+func requiredPermission(r *http.Request) rbac.Permission {
+	if permission, ok := routePermissions[r.Pattern]; ok {
+		return permission
 	}
-	return "user"
+	return rbac.All
 }
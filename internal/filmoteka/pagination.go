@@ -0,0 +1,128 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var errInvalidPage = errors.New("invalid pagination parameters")
+
+// parsePage читает параметры limit, offset и cursor. Слишком большой limit
+// ограничивается сверху, а не считается ошибкой. offset и cursor - два разных
+// способа листать выборку, поэтому вместе они не принимаются.
+func (f *Filmoteka) parsePage(r *http.Request) (db.Page, error) {
+	queryValues := r.URL.Query()
+
+	page := db.Page{Limit: f.Config.HTTP.Pagination.DefaultLimit}
+	if page.Limit <= 0 {
+		page.Limit = defaultPageLimit
+	}
+	maxLimit := f.Config.HTTP.Pagination.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = maxPageLimit
+	}
+
+	if limitParam := queryValues.Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return db.Page{}, fmt.Errorf("%w: limit %q", errInvalidPage, limitParam)
+		}
+		page.Limit = limit
+	}
+	page.Limit = min(page.Limit, maxLimit)
+
+	if offsetParam := queryValues.Get("offset"); offsetParam != "" {
+		offset, err := strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return db.Page{}, fmt.Errorf("%w: offset %q", errInvalidPage, offsetParam)
+		}
+		page.Offset = offset
+	}
+
+	if cursorParam := queryValues.Get("cursor"); cursorParam != "" {
+		cursor, err := db.DecodeCursor(cursorParam)
+		if err != nil {
+			return db.Page{}, errors.Join(errInvalidPage, err)
+		}
+		page.Cursor = cursor
+	}
+	if page.Cursor != nil && queryValues.Has("offset") {
+		return db.Page{}, fmt.Errorf("%w: offset cannot be combined with cursor", errInvalidPage)
+	}
+
+	return page, nil
+}
+
+// writePage отдаёт страницу списка: общее число записей в X-Total-Count и
+// ссылки на соседние страницы в Link. Если клиент листает по offset, ссылки
+// строятся по offset, иначе по курсорам.
+func writePage[T any](w http.ResponseWriter, r *http.Request, page db.Page, info db.PageInfo, items []T) {
+	writePageHeaders(w, r, page, info)
+	if items == nil {
+		items = []T{}
+	}
+	json.NewEncoder(w).Encode(items)
+}
+
+// writeFacetedPage отдаёт страницу вместе с фасетами: вместо массива тело
+// становится объектом {"items": [...], "facets": {...}}.
+func writeFacetedPage[T any](w http.ResponseWriter, r *http.Request, page db.Page, info db.PageInfo, items []T, facets Facets) {
+	writePageHeaders(w, r, page, info)
+	if items == nil {
+		items = []T{}
+	}
+	json.NewEncoder(w).Encode(struct {
+		Items  []T    `json:"items"`
+		Facets Facets `json:"facets"`
+	}{items, facets})
+}
+
+func writePageHeaders(w http.ResponseWriter, r *http.Request, page db.Page, info db.PageInfo) {
+	var links []string
+	link := func(rel string, set map[string]string) {
+		u := *r.URL
+		queryValues := u.Query()
+		queryValues.Set("limit", strconv.Itoa(page.Limit))
+		for name, value := range set {
+			if value == "" {
+				queryValues.Del(name)
+			} else {
+				queryValues.Set(name, value)
+			}
+		}
+		u.RawQuery = queryValues.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+
+	if r.URL.Query().Has("offset") {
+		if info.HasMore {
+			link("next", map[string]string{"offset": strconv.Itoa(page.Offset + page.Limit), "cursor": ""})
+		}
+		if page.Offset > 0 {
+			link("prev", map[string]string{"offset": strconv.Itoa(max(page.Offset-page.Limit, 0)), "cursor": ""})
+		}
+		link("first", map[string]string{"offset": "0", "cursor": ""})
+	} else {
+		if info.Next != nil {
+			link("next", map[string]string{"cursor": info.Next.Encode()})
+		}
+		if info.Prev != nil {
+			link("prev", map[string]string{"cursor": info.Prev.Encode()})
+		}
+		link("first", map[string]string{"cursor": ""})
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(info.Total))
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("Content-Type", "application/json")
+}
@@ -0,0 +1,181 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// acceptPatch - форматы тела PATCH; отдаётся в Accept-Patch при ответе 415.
+var acceptPatch = strings.Join([]string{mergePatchContentType, jsonPatchContentType}, ", ")
+
+// patchField - член тела merge patch (RFC 7396). Отличает отсутствующий член
+// (Set = false) от переданного null (Null = true) и от нулевого значения.
+type patchField[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// UnmarshalJSON отмечает член переданным даже при значении неверного типа:
+// ошибку разбора decodeJSON возвращает как нарушение для поля.
+func (f *patchField[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &f.Value)
+}
+
+// optional переводит член в изменение поля хранилища: null сбрасывает
+// поле в NULL.
+func (f patchField[T]) optional() db.Optional[T] {
+	return db.Optional[T]{Set: f.Set, Null: f.Null, Value: f.Value}
+}
+
+// MoviePatchRequest - тело PATCH /movies/{id} и POST /movies/update. Фильм
+// выбирает id из тела только в устаревшем маршруте; в PATCH /movies/{id} id в
+// теле должен совпадать с id в пути.
+type MoviePatchRequest struct {
+	Id          patchField[int]    `json:"id"`
+	Title       patchField[string] `json:"title"`
+	Description patchField[string] `json:"description"`
+	ReleaseDate patchField[string] `json:"release_date"`
+}
+
+// request возвращает переданные значения в виде MovieRequest для проверки
+// правилами movieRules.
+func (req MoviePatchRequest) request() MovieRequest {
+	return MovieRequest{
+		Id:             req.Id.Value,
+		Title:          req.Title.Value,
+		Description:    req.Description.Value,
+		ReleaseDateStr: req.ReleaseDate.Value,
+	}
+}
+
+func (req MoviePatchRequest) patch() db.MoviePatch {
+	return db.MoviePatch{
+		Title:       req.Title.optional(),
+		Description: req.Description.optional(),
+		ReleaseDate: db.Optional[time.Time]{Set: req.ReleaseDate.Set, Null: req.ReleaseDate.Null, Value: releaseDate(req.request())},
+	}
+}
+
+// ActorPatchRequest - тело PATCH /actors/{id} и POST /actors/update. Актёра
+// выбирает id из тела только в устаревшем маршруте; в PATCH /actors/{id} id в
+// теле должен совпадать с id в пути.
+type ActorPatchRequest struct {
+	Id        patchField[int]       `json:"id"`
+	Name      patchField[string]    `json:"name"`
+	Gender    patchField[string]    `json:"gender"`
+	Birthdate patchField[time.Time] `json:"birthdate"`
+}
+
+// request возвращает переданные значения в виде db.Actor для проверки
+// правилами actorRules.
+func (req ActorPatchRequest) request() db.Actor {
+	return db.Actor{
+		Id:        req.Id.Value,
+		Name:      req.Name.Value,
+		Gender:    req.Gender.Value,
+		Birthdate: &req.Birthdate.Value,
+	}
+}
+
+func (req ActorPatchRequest) patch() db.ActorPatch {
+	return db.ActorPatch{
+		Name:      req.Name.optional(),
+		Gender:    req.Gender.optional(),
+		Birthdate: req.Birthdate.optional(),
+	}
+}
+
+// patchOperation - операция JSON Patch (RFC 6902).
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodePatch читает тело PATCH в v. application/json и
+// application/merge-patch+json разбираются как merge patch,
+// application/json-patch+json сводится к нему. Нарушения разбора
+// возвращаются, как у decodeJSON; если decodePatch вернул false, ответ уже
+// записан.
+func (f *Filmoteka) decodePatch(w http.ResponseWriter, r *http.Request, v interface{}, errorKey i18n.Key) ([]violation, bool) {
+	defer r.Body.Close()
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+
+	switch mediaType {
+	case "application/json", mergePatchContentType:
+		return f.decodeJSON(w, r, r.Body, v, errorKey)
+	case jsonPatchContentType:
+		return f.decodeJSONPatch(w, r, v, errorKey)
+	default:
+		w.Header().Set("Accept-Patch", acceptPatch)
+		writeError(w, r, codeUnsupportedMediaType, i18n.UnsupportedPatchType, acceptPatch)
+		return nil, false
+	}
+}
+
+// decodeJSONPatch сводит операции JSON Patch к merge patch и разбирает его в
+// v. Поддерживаются add и replace, задающие поле, и remove, сбрасывающий его
+// как null; операции применяются по порядку. Пути указывают только на поля
+// верхнего уровня.
+func (f *Filmoteka) decodeJSONPatch(w http.ResponseWriter, r *http.Request, v interface{}, errorKey i18n.Key) ([]violation, bool) {
+	var operations []patchOperation
+	if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, errorKey)
+		return nil, false
+	}
+
+	merge := make(map[string]json.RawMessage)
+	for _, operation := range operations {
+		member, ok := strings.CutPrefix(operation.Path, "/")
+		if !ok || member == "" || strings.Contains(member, "/") {
+			writeError(w, r, codeInvalidBody, i18n.InvalidPatchPath, operation.Path)
+			return nil, false
+		}
+		member = strings.NewReplacer("~1", "/", "~0", "~").Replace(member)
+
+		switch operation.Op {
+		case "add", "replace":
+			if operation.Value == nil {
+				writeError(w, r, codeInvalidBody, i18n.PatchValueRequired, operation.Op)
+				return nil, false
+			}
+			merge[member] = operation.Value
+		case "remove":
+			merge[member] = json.RawMessage("null")
+		default:
+			writeError(w, r, codeInvalidBody, i18n.UnsupportedPatchOperation, operation.Op)
+			return nil, false
+		}
+	}
+
+	body, err := json.Marshal(merge)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, errorKey)
+		return nil, false
+	}
+	return f.decodeJSON(w, r, bytes.NewReader(body), v, errorKey)
+}
@@ -0,0 +1,105 @@
+package filmoteka
+
+import (
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"net/http"
+)
+
+// errorCode - машиночитаемый код ошибки. Коды стабильны: клиенты различают
+// ошибки по коду, а не по тексту detail.
+type errorCode string
+
+const (
+	codeInvalidBody          errorCode = "invalid_body"
+	codeInvalidParameter     errorCode = "invalid_parameter"
+	codeValidationFailed     errorCode = "validation_failed"
+	codeInvalidFields        errorCode = "invalid_fields"
+	codeUnauthorized         errorCode = "unauthorized"
+	codeInvalidCredentials   errorCode = "invalid_credentials"
+	codeForbidden            errorCode = "forbidden"
+	codeNotFound             errorCode = "not_found"
+	codeMethodNotAllowed     errorCode = "method_not_allowed"
+	codeUnsupportedMediaType errorCode = "unsupported_media_type"
+	codeConflict             errorCode = "conflict"
+	codeInternal             errorCode = "internal_error"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// problemTypeBase - префикс поля type; полный URI описан в specification.yaml.
+	problemTypeBase = "/problems/"
+)
+
+type problemType struct {
+	status int
+	title  i18n.Key
+}
+
+var problemTypes = map[errorCode]problemType{
+	codeInvalidBody:          {http.StatusBadRequest, i18n.ProblemInvalidBody},
+	codeInvalidParameter:     {http.StatusBadRequest, i18n.ProblemInvalidParameter},
+	codeValidationFailed:     {http.StatusBadRequest, i18n.ProblemValidationFailed},
+	codeInvalidFields:        {http.StatusUnprocessableEntity, i18n.ProblemInvalidFields},
+	codeUnauthorized:         {http.StatusUnauthorized, i18n.ProblemUnauthorized},
+	codeInvalidCredentials:   {http.StatusUnauthorized, i18n.ProblemInvalidCredentials},
+	codeForbidden:            {http.StatusForbidden, i18n.ProblemForbidden},
+	codeNotFound:             {http.StatusNotFound, i18n.ProblemNotFound},
+	codeMethodNotAllowed:     {http.StatusMethodNotAllowed, i18n.ProblemMethodNotAllowed},
+	codeUnsupportedMediaType: {http.StatusUnsupportedMediaType, i18n.ProblemUnsupportedMediaType},
+	codeConflict:             {http.StatusConflict, i18n.ProblemConflict},
+	codeInternal:             {http.StatusInternalServerError, i18n.ProblemInternal},
+}
+
+// Problem - тело ответа с ошибкой в формате RFC 7807 (application/problem+json).
+// Code и RequestID - расширения формата.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      errorCode    `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError - ошибка в отдельном поле тела запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// newProblem собирает ответ с ошибкой; title и detail переводятся на язык
+// запроса.
+func newProblem(r *http.Request, code errorCode, detail i18n.Key, args ...interface{}) Problem {
+	problemType := problemTypes[code]
+	return Problem{
+		Type:      problemTypeBase + string(code),
+		Title:     message(r, problemType.title),
+		Status:    problemType.status,
+		Detail:    message(r, detail, args...),
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// writeError отвечает ошибкой с кодом code; статус определяется кодом.
+func writeError(w http.ResponseWriter, r *http.Request, code errorCode, detail i18n.Key, args ...interface{}) {
+	writeProblem(w, newProblem(r, code, detail, args...))
+}
+
+// writeFieldError отвечает ошибкой проверки одного поля тела запроса.
+func writeFieldError(w http.ResponseWriter, r *http.Request, field string, detail i18n.Key) {
+	problem := newProblem(r, codeValidationFailed, detail)
+	problem.Errors = []FieldError{{Field: field, Message: problem.Detail}}
+	writeProblem(w, problem)
+}
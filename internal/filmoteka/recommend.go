@@ -0,0 +1,157 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRecommendationsLimit = 10
+	maxRecommendationsLimit     = 50
+	defaultRefreshInterval      = 10 * time.Minute
+)
+
+var defaultSimilarityWeights = db.SimilarityWeights{
+	Actors:      0.35,
+	Genres:      0.3,
+	Era:         0.1,
+	Ratings:     0.25,
+	EraYears:    10,
+	MinCoRaters: 2,
+	Neighbours:  20,
+}
+
+// similarityWeights берёт веса из секции recommendations конфигурации;
+// незаданные значения заменяются значениями по умолчанию.
+func (f *Filmoteka) similarityWeights() db.SimilarityWeights {
+	cfg := f.Config.Recommendations
+	weights := defaultSimilarityWeights
+	if w := cfg.Weights; w.Actors+w.Genres+w.Era+w.Ratings > 0 {
+		weights.Actors, weights.Genres, weights.Era, weights.Ratings = w.Actors, w.Genres, w.Era, w.Ratings
+	}
+	if cfg.EraYears > 0 {
+		weights.EraYears = cfg.EraYears
+	}
+	if cfg.MinCoRaters > 0 {
+		weights.MinCoRaters = cfg.MinCoRaters
+	}
+	if cfg.Neighbours > 0 {
+		weights.Neighbours = cfg.Neighbours
+	}
+	return weights
+}
+
+// RefreshRecommendations пересчитывает похожие фильмы при запуске и затем
+// с интервалом recommendations.refresh_interval, пока не отменён ctx.
+func (f *Filmoteka) RefreshRecommendations(ctx context.Context) {
+	interval := f.Config.Recommendations.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f.rebuildSimilarities()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Filmoteka) rebuildSimilarities() (int, error) {
+	start := time.Now()
+	pairs, err := f.Store.RebuildSimilarities(f.similarityWeights())
+	if err != nil {
+		f.Logger.Warn("Error rebuilding similar movies", "error", err)
+		return 0, err
+	}
+	f.Logger.Info("Similar movies rebuilt", "pairs", pairs, "duration", time.Since(start))
+	return pairs, nil
+}
+
+// parseRecommendationsLimit читает параметр limit для списков рекомендаций.
+func parseRecommendationsLimit(r *http.Request) (int, error) {
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam == "" {
+		return defaultRecommendationsLimit, nil
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return min(limit, maxRecommendationsLimit), nil
+}
+
+func (f *Filmoteka) handleGetSimilarMovies(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+	limit, err := parseRecommendationsLimit(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	similar, err := f.Store.GetSimilarMovies(movieID, limit)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting similar movies", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.SimilarMoviesGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(similar)
+	f.Logger.Info("Similar movies", "movie_id", movieID, "total", len(similar))
+}
+
+func (f *Filmoteka) handleGetRecommendations(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	limit, err := parseRecommendationsLimit(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	recommendations, err := f.Store.GetRecommendations(user.Id, limit)
+	if err != nil {
+		f.Logger.Warn("Error getting recommendations", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.RecommendationsGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recommendations)
+	f.Logger.Info("Recommendations", "user_id", user.Id, "total", len(recommendations))
+}
+
+// handleRebuildRecommendations пересчитывает похожие фильмы, не дожидаясь
+// фоновой задачи.
+func (f *Filmoteka) handleRebuildRecommendations(w http.ResponseWriter, r *http.Request) {
+	pairs, err := f.rebuildSimilarities()
+	if err != nil {
+		writeError(w, r, codeInternal, i18n.RecommendationsRebuildError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"pairs": pairs})
+}
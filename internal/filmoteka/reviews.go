@@ -0,0 +1,243 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"TestVK/internal/rbac"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// ReviewRequest - тело PUT /movies/{id}/reviews/me.
+type ReviewRequest struct {
+	Score *int   `json:"score"`
+	Body  string `json:"body"`
+}
+
+// ReviewStatusRequest - тело PATCH /reviews/{id}.
+type ReviewStatusRequest struct {
+	Status string `json:"status"`
+}
+
+func (f *Filmoteka) ratingPrior() db.RatingPrior {
+	return db.RatingPrior{
+		MinVotes: f.Config.Ratings.BayesianMinVotes,
+		Mean:     f.Config.Ratings.BayesianPrior,
+	}
+}
+
+func (f *Filmoteka) handleGetMovieReviews(w http.ResponseWriter, r *http.Request) {
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	includeHidden, _ := strconv.ParseBool(r.URL.Query().Get("include_hidden"))
+	if includeHidden {
+		user, _ := UserFromContext(r.Context())
+		if !f.Policy.Allowed(user.Role, rbac.ReviewsModerate) {
+			writeError(w, r, codeForbidden, i18n.HiddenReviewsForbidden)
+			return
+		}
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	reviews, info, err := f.Store.GetMovieReviews(movieID, includeHidden, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting reviews", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ReviewsGetError)
+		return
+	}
+
+	writePage(w, r, page, info, reviews)
+	f.Logger.Info("Movie reviews", "movie_id", movieID, "total", info.Total)
+}
+
+func (f *Filmoteka) handleGetMyReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	review, err := f.Store.GetUserReview(movieID, user.Id)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ReviewNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error getting review", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ReviewGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+func (f *Filmoteka) handleSaveMyReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	var reviewReq ReviewRequest
+	err = json.NewDecoder(r.Body).Decode(&reviewReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	if reviewReq.Score == nil {
+		writeFieldError(w, r, "score", i18n.ScoreRequired)
+		return
+	}
+
+	review, created, err := f.Store.SaveReview(db.Review{
+		MovieID: movieID,
+		UserID:  user.Id,
+		Score:   *reviewReq.Score,
+		Body:    reviewReq.Body,
+	}, f.ratingPrior())
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			writeError(w, r, codeNotFound, i18n.MovieNotFound)
+		case errors.Is(err, db.ErrInvalidScore):
+			writeFieldError(w, r, "score", i18n.InvalidScore)
+		case errors.Is(err, db.ErrForeignKeyViolation):
+			writeError(w, r, codeForbidden, i18n.ActionRequiresAccount)
+		default:
+			f.Logger.Warn("Error saving review", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.ReviewSaveError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(review)
+	f.Logger.Info("Review saved", "id", review.ID, "movie_id", movieID, "user_id", user.Id, "created", created)
+}
+
+func (f *Filmoteka) handleDeleteMyReview(w http.ResponseWriter, r *http.Request) {
+	user, ok := registeredUser(w, r)
+	if !ok {
+		return
+	}
+	movieID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get movie id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidMovieID)
+		return
+	}
+
+	err = f.Store.DeleteUserReview(movieID, user.Id, f.ratingPrior())
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ReviewNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error deleting review", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ReviewDeleteError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.ReviewDeleted)))
+	f.Logger.Info("Review deleted", "movie_id", movieID, "user_id", user.Id)
+}
+
+func (f *Filmoteka) handleModerateReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get review id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidReviewID)
+		return
+	}
+
+	var statusReq ReviewStatusRequest
+	err = json.NewDecoder(r.Body).Decode(&statusReq)
+	if err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, i18n.InvalidBody)
+		return
+	}
+	defer r.Body.Close()
+
+	review, err := f.Store.SetReviewStatus(reviewID, statusReq.Status, f.ratingPrior())
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			writeError(w, r, codeNotFound, i18n.ReviewNotFound)
+		case errors.Is(err, db.ErrUnknownReviewStatus):
+			writeFieldError(w, r, "status", i18n.UnknownReviewStatus)
+		default:
+			f.Logger.Warn("Error moderating review", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.ReviewStatusError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+	f.Logger.Info("Review moderated", "id", reviewID, "status", review.Status)
+}
+
+func (f *Filmoteka) handleDeleteReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := resourceID(r, "id")
+	if err != nil {
+		f.Logger.Info("Can't get review id", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidReviewID)
+		return
+	}
+
+	err = f.Store.DeleteReview(reviewID, f.ratingPrior())
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, r, codeNotFound, i18n.ReviewNotFound)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error deleting review", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ReviewDeleteError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(message(r, i18n.ReviewDeleted)))
+	f.Logger.Info("Review deleted by moderator", "id", reviewID)
+}
@@ -0,0 +1,172 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// handleSearchMovies - полнотекстовый поиск фильмов по параметру q. Результаты
+// упорядочены по релевантности и содержат фрагменты с найденными словами.
+func (f *Filmoteka) handleSearchMovies(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		writeError(w, r, codeInvalidParameter, i18n.SearchQueryRequired)
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	withFacets, err := parseFacets(r)
+	if err != nil {
+		f.Logger.Info("Invalid facets", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidFilter)
+		return
+	}
+
+	hits, info, err := f.Store.SearchMovies(text, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching movies", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.MoviesSearchError)
+		return
+	}
+
+	if withFacets {
+		genres, err := f.Store.SearchMovieGenreFacets(text)
+		if err != nil {
+			f.Logger.Warn("Error counting genre facets", "status", http.StatusInternalServerError, "error", err)
+			writeError(w, r, codeInternal, i18n.MoviesSearchError)
+			return
+		}
+		writeFacetedPage(w, r, page, info, hits, Facets{Genres: genres})
+		f.Logger.Info("Movies search", "q", text, "total", info.Total)
+		return
+	}
+
+	writePage(w, r, page, info, hits)
+	f.Logger.Info("Movies search", "q", text, "total", info.Total)
+}
+
+func (f *Filmoteka) handleSearchActors(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		writeError(w, r, codeInvalidParameter, i18n.SearchQueryRequired)
+		return
+	}
+
+	page, err := f.parsePage(r)
+	if err != nil {
+		f.Logger.Info("Invalid page", "status", http.StatusBadRequest, "error", err)
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+
+	hits, info, err := f.Store.SearchActors(text, page)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+		return
+	}
+	if err != nil {
+		f.Logger.Warn("Error searching actors", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.ActorsSearchError)
+		return
+	}
+
+	writePage(w, r, page, info, hits)
+	f.Logger.Info("Actors search", "q", text, "total", info.Total)
+}
+
+const (
+	defaultSimilarityThreshold = 0.3
+	defaultSuggestions         = 5
+	maxAutocompleteLimit       = 20
+)
+
+// fuzzySearch включает поиск по сходству триграмм вместо точного совпадения.
+func fuzzySearch(r *http.Request) bool {
+	fuzzy, _ := strconv.ParseBool(r.URL.Query().Get("fuzzy"))
+	return fuzzy
+}
+
+func (f *Filmoteka) similarityThreshold() float64 {
+	if threshold := f.Config.Search.SimilarityThreshold; threshold > 0 {
+		return threshold
+	}
+	return defaultSimilarityThreshold
+}
+
+func (f *Filmoteka) suggestionsLimit() int {
+	if limit := f.Config.Search.Suggestions; limit > 0 {
+		return limit
+	}
+	return defaultSuggestions
+}
+
+// suggest добавляет к пустому результату точного поиска заголовки
+// X-Did-You-Mean с похожими именами или названиями. Ошибка подсказок не
+// мешает ответу на сам запрос.
+func (f *Filmoteka) suggest(w http.ResponseWriter, suggestFunc func(string, float64, int) ([]db.Suggestion, error), text string) {
+	suggestions, err := suggestFunc(text, f.similarityThreshold(), f.suggestionsLimit())
+	if err != nil {
+		f.Logger.Warn("Error getting suggestions", "text", text, "error", err)
+		return
+	}
+
+	for _, suggestion := range suggestions {
+		w.Header().Add("X-Did-You-Mean", url.QueryEscape(suggestion.Text))
+	}
+}
+
+func (f *Filmoteka) handleAutocompleteMovies(w http.ResponseWriter, r *http.Request) {
+	f.autocomplete(w, r, f.Store.SuggestMovies)
+}
+
+func (f *Filmoteka) handleAutocompleteActors(w http.ResponseWriter, r *http.Request) {
+	f.autocomplete(w, r, f.Store.SuggestActors)
+}
+
+func (f *Filmoteka) autocomplete(w http.ResponseWriter, r *http.Request, suggestFunc func(string, float64, int) ([]db.Suggestion, error)) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		writeError(w, r, codeInvalidParameter, i18n.SearchQueryRequired)
+		return
+	}
+
+	limit := f.suggestionsLimit()
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			writeError(w, r, codeInvalidParameter, i18n.InvalidPage)
+			return
+		}
+	}
+	limit = min(limit, maxAutocompleteLimit)
+
+	suggestions, err := suggestFunc(text, f.similarityThreshold(), limit)
+	if err != nil {
+		f.Logger.Warn("Error getting suggestions", "status", http.StatusInternalServerError, "error", err)
+		writeError(w, r, codeInternal, i18n.SuggestionsGetError)
+		return
+	}
+	if suggestions == nil {
+		suggestions = []db.Suggestion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
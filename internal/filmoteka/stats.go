@@ -0,0 +1,233 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStatsCacheTTL = 5 * time.Minute
+	defaultStatsLimit    = 10
+	maxStatsLimit        = 100
+)
+
+// statsCache хранит посчитанные агрегаты до истечения ttl. Статистика не
+// сбрасывается при изменении каталога и может отставать от него на ttl.
+type statsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[statsKey]statsEntry
+}
+
+// statsParams - разобранные параметры запроса статистики; параметры, которые
+// отчёт не принимает, остаются нулевыми.
+type statsParams struct {
+	filter db.MovieFilter
+	years  int
+	limit  int
+	source string
+}
+
+// statsKey - ключ кэша: путь отчёта и разобранные параметры. Посторонние
+// параметры запроса, их порядок и запись значений не порождают новых записей.
+type statsKey struct {
+	path     string
+	from, to string
+	years    int
+	limit    int
+	source   string
+}
+
+func (p statsParams) key(path string) statsKey {
+	return statsKey{
+		path:   path,
+		from:   statsDate(p.filter.ReleasedFrom),
+		to:     statsDate(p.filter.ReleasedTo),
+		years:  p.years,
+		limit:  p.limit,
+		source: p.source,
+	}
+}
+
+func statsDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(dateLayout)
+}
+
+type statsEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newStatsCache(ttl time.Duration) *statsCache {
+	if ttl <= 0 {
+		ttl = defaultStatsCacheTTL
+	}
+	return &statsCache{ttl: ttl, entries: make(map[statsKey]statsEntry)}
+}
+
+// get возвращает значение по ключу или считает его через compute. Ошибки
+// compute не кэшируются.
+func (c *statsCache) get(key statsKey, compute func() (interface{}, error)) (interface{}, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := compute()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = statsEntry{value: value, expires: now.Add(c.ttl)}
+	return value, nil
+}
+
+// parseStatsFilter читает необязательный диапазон дат выхода фильмов from и to.
+func parseStatsFilter(r *http.Request) (db.MovieFilter, error) {
+	var filter db.MovieFilter
+	var err error
+	if from := r.URL.Query().Get("from"); from != "" {
+		if filter.ReleasedFrom, err = parseDate(from); err != nil {
+			return db.MovieFilter{}, err
+		}
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		if filter.ReleasedTo, err = parseDate(to); err != nil {
+			return db.MovieFilter{}, err
+		}
+	}
+	return filter, nil
+}
+
+// parseStatsLimit читает параметр limit для рейтингов в статистике.
+func parseStatsLimit(r *http.Request) (int, error) {
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam == "" {
+		return defaultStatsLimit, nil
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 || limit > maxStatsLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxStatsLimit)
+	}
+	return limit, nil
+}
+
+// serveStats отдаёт агрегат из кэша; ключ - путь и разобранные параметры.
+func (f *Filmoteka) serveStats(w http.ResponseWriter, r *http.Request, params statsParams, compute func() (interface{}, error)) {
+	stats, err := f.stats.get(params.key(r.URL.Path), compute)
+	if err != nil {
+		f.Logger.Warn("Error getting stats", "status", http.StatusInternalServerError, "path", r.URL.Path, "error", err)
+		writeError(w, r, codeInternal, i18n.StatsGetError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleGetMovieStats считает фильмы и средний рейтинг по годам или по
+// десятилетиям (by=decade).
+func (f *Filmoteka) handleGetMovieStats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidDate)
+		return
+	}
+
+	years := 1
+	switch r.URL.Query().Get("by") {
+	case "", "year":
+	case "decade":
+		years = 10
+	default:
+		writeError(w, r, codeInvalidParameter, i18n.InvalidStatsPeriod)
+		return
+	}
+
+	f.serveStats(w, r, statsParams{filter: filter, years: years}, func() (interface{}, error) {
+		return f.Store.GetMoviesByPeriod(filter, years)
+	})
+}
+
+func (f *Filmoteka) handleGetGenreStats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidDate)
+		return
+	}
+
+	f.serveStats(w, r, statsParams{filter: filter}, func() (interface{}, error) {
+		return f.Store.GetGenreStats(filter)
+	})
+}
+
+func (f *Filmoteka) handleGetProlificActors(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidDate)
+		return
+	}
+	limit, err := parseStatsLimit(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidStatsLimit, maxStatsLimit)
+		return
+	}
+
+	f.serveStats(w, r, statsParams{filter: filter, limit: limit}, func() (interface{}, error) {
+		return f.Store.GetProlificActors(filter, limit)
+	})
+}
+
+func (f *Filmoteka) handleGetActorAgeStats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidDate)
+		return
+	}
+
+	f.serveStats(w, r, statsParams{filter: filter}, func() (interface{}, error) {
+		return f.Store.GetActorAgeStats(filter)
+	})
+}
+
+// handleGetRatingHistogram строит гистограмму рейтингов фильмов или, при
+// source=reviews, оценок из опубликованных отзывов.
+func (f *Filmoteka) handleGetRatingHistogram(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		writeError(w, r, codeInvalidParameter, i18n.InvalidDate)
+		return
+	}
+	source := r.URL.Query().Get("source")
+	switch source {
+	case "":
+		source = db.RatingSourceMovies
+	case db.RatingSourceMovies, db.RatingSourceReviews:
+	default:
+		writeError(w, r, codeInvalidParameter, i18n.InvalidRatingSource)
+		return
+	}
+
+	f.serveStats(w, r, statsParams{filter: filter, source: source}, func() (interface{}, error) {
+		return f.Store.GetRatingHistogram(filter, source)
+	})
+}
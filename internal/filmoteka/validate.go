@@ -0,0 +1,334 @@
+package filmoteka
+
+import (
+	"TestVK/internal/db"
+	"TestVK/internal/i18n"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// dateLayout - формат дат в теле запроса и в сообщениях об ошибках.
+	dateLayout = "2006.01.02"
+	// releaseDateHorizonYears - на сколько лет вперёд можно указать дату
+	// выхода анонсированного фильма.
+	releaseDateHorizonYears = 10
+)
+
+var (
+	earliestBirthdate   = time.Date(1850, time.January, 1, 0, 0, 0, 0, time.UTC)
+	earliestReleaseDate = time.Date(1888, time.January, 1, 0, 0, 0, 0, time.UTC)
+	genders             = []string{db.GenderMale, db.GenderFemale, db.GenderOther}
+)
+
+// actorRules проверяют заданные поля актёра; requiredActorRules добавляются
+// к ним при создании и полной замене.
+var (
+	actorRules = []rule[db.Actor]{
+		maxLength("name", actorName, db.MaxActorNameLength, i18n.ActorNameTooLong),
+		oneOf("gender", func(actor db.Actor) string { return actor.Gender }, genders, i18n.InvalidGender),
+		dateBetween("birthdate", actorBirthdate,
+			earliestBirthdate, today, i18n.BirthdateOutOfRange, earliestBirthdate.Format(dateLayout)),
+	}
+	requiredActorRules = []rule[db.Actor]{
+		required("name", actorName, i18n.ActorNameRequired),
+	}
+	// actorPatchRules проверяют переданные поля по actorRules; имя нельзя
+	// сбросить.
+	actorPatchRules = append([]rule[ActorPatchRequest]{
+		notCleared("name", func(req ActorPatchRequest) patchField[string] { return req.Name }, i18n.ActorNameRequired),
+	}, liftRules(actorRules, ActorPatchRequest.request)...)
+	// legacyUpdateActorRules - POST /actors/update, где id передаётся в теле.
+	legacyUpdateActorRules = []rule[ActorPatchRequest]{
+		check("id", func(req ActorPatchRequest) bool { return req.Id.Value != 0 }, i18n.ActorIDRequired),
+	}
+)
+
+// movieRules проверяют заданные поля фильма; requiredMovieRules добавляются
+// к ним при создании и полной замене.
+var (
+	movieRules = []rule[MovieRequest]{
+		maxLength("title", movieTitle, db.MaxMovieTitleLength, i18n.MovieTitleTooLong),
+		check("release_date", func(req MovieRequest) bool {
+			_, err := parseDate(req.ReleaseDateStr)
+			return req.ReleaseDateStr == "" || err == nil
+		}, i18n.InvalidReleaseDate),
+		dateBetween("release_date", releaseDate, earliestReleaseDate, releaseDateHorizon,
+			i18n.ReleaseDateOutOfRange, earliestReleaseDate.Format(dateLayout), releaseDateHorizonYears),
+	}
+	requiredMovieRules = []rule[MovieRequest]{
+		required("title", movieTitle, i18n.MovieTitleRequired),
+		required("release_date", func(req MovieRequest) string { return req.ReleaseDateStr }, i18n.ReleaseDateRequired),
+	}
+	// moviePatchRules проверяют переданные поля по movieRules; название и дату
+	// выхода нельзя сбросить.
+	moviePatchRules = append([]rule[MoviePatchRequest]{
+		notCleared("title", func(req MoviePatchRequest) patchField[string] { return req.Title }, i18n.MovieTitleRequired),
+		notCleared("release_date", func(req MoviePatchRequest) patchField[string] { return req.ReleaseDate }, i18n.ReleaseDateRequired),
+	}, liftRules(movieRules, MoviePatchRequest.request)...)
+	// legacyUpdateMovieRules - POST /movies/update, где id передаётся в теле.
+	legacyUpdateMovieRules = []rule[MoviePatchRequest]{
+		check("id", func(req MoviePatchRequest) bool { return req.Id.Value != 0 }, i18n.MovieIDRequired),
+	}
+)
+
+// registerRules проверяют учётные данные нового пользователя.
+var registerRules = []rule[CredentialsRequest]{
+	required("username", func(c CredentialsRequest) string { return c.Username }, i18n.InvalidUsername),
+	check("username", func(c CredentialsRequest) bool {
+		return utf8.RuneCountInString(c.Username) <= maxUsernameLength
+	}, i18n.InvalidUsername),
+	check("password", func(c CredentialsRequest) bool { return len(c.Password) >= minPasswordLength }, i18n.PasswordTooShort),
+	check("password", func(c CredentialsRequest) bool { return len(c.Password) <= maxPasswordBytes }, i18n.PasswordTooLong),
+}
+
+func actorName(actor db.Actor) string { return actor.Name }
+
+// actorBirthdate возвращает нулевое время, если дата рождения не указана.
+func actorBirthdate(actor db.Actor) time.Time {
+	if actor.Birthdate == nil {
+		return time.Time{}
+	}
+	return *actor.Birthdate
+}
+
+func movieTitle(req MovieRequest) string { return req.Title }
+
+// releaseDate возвращает нулевое время, если дата не указана или указана
+// неверно: формат проверяет отдельное правило.
+func releaseDate(req MovieRequest) time.Time {
+	date, _ := parseDate(req.ReleaseDateStr)
+	return date
+}
+
+func today() time.Time {
+	return time.Now().UTC()
+}
+
+func releaseDateHorizon() time.Time {
+	return today().AddDate(releaseDateHorizonYears, 0, 0)
+}
+
+// rule - правило проверки одного поля значения типа T. Если правило нарушено,
+// в ответ попадает сообщение key с аргументами args.
+type rule[T any] struct {
+	field string
+	valid func(T) bool
+	key   i18n.Key
+	args  []interface{}
+}
+
+func check[T any](field string, valid func(T) bool, key i18n.Key, args ...interface{}) rule[T] {
+	return rule[T]{field: field, valid: valid, key: key, args: args}
+}
+
+// required требует непустую строку; строка из одних пробелов считается пустой.
+func required[T any](field string, value func(T) string, key i18n.Key) rule[T] {
+	return check(field, func(v T) bool { return strings.TrimSpace(value(v)) != "" }, key)
+}
+
+// sameID допускает тело без id или с тем же id, что в пути запроса: id
+// ресурса задаёт только путь.
+func sameID[T any](pathID int, id func(T) int) []rule[T] {
+	return []rule[T]{check("id", func(v T) bool {
+		bodyID := id(v)
+		return bodyID == 0 || bodyID == pathID
+	}, i18n.IDMismatch)}
+}
+
+// notCleared запрещает сбрасывать обязательное поле через null или пустую
+// строку; отсутствующее поле допустимо.
+func notCleared[T any](field string, value func(T) patchField[string], key i18n.Key) rule[T] {
+	return check(field, func(v T) bool {
+		member := value(v)
+		return !member.Set || strings.TrimSpace(member.Value) != ""
+	}, key)
+}
+
+// maxLength ограничивает длину строки в символах, как VARCHAR(limit).
+func maxLength[T any](field string, value func(T) string, limit int, key i18n.Key) rule[T] {
+	return check(field, func(v T) bool { return utf8.RuneCountInString(value(v)) <= limit }, key, limit)
+}
+
+// oneOf допускает пустую строку или одно из значений allowed.
+func oneOf[T any](field string, value func(T) string, allowed []string, key i18n.Key) rule[T] {
+	return check(field, func(v T) bool {
+		s := value(v)
+		return s == "" || slices.Contains(allowed, s)
+	}, key, strings.Join(allowed, ", "))
+}
+
+// dateBetween допускает нулевую дату или дату от earliest до latest()
+// включительно; latest вычисляется в момент проверки.
+func dateBetween[T any](field string, value func(T) time.Time, earliest time.Time, latest func() time.Time, key i18n.Key, args ...interface{}) rule[T] {
+	return check(field, func(v T) bool {
+		date := value(v)
+		return date.IsZero() || !date.Before(earliest) && !date.After(latest())
+	}, key, args...)
+}
+
+// liftRules переносит правила для U на T, из которого U получается через
+// convert; так правила сущности проверяют и тело частичного обновления.
+func liftRules[T, U any](rules []rule[U], convert func(T) U) []rule[T] {
+	lifted := make([]rule[T], len(rules))
+	for i, r := range rules {
+		valid := r.valid
+		lifted[i] = rule[T]{field: r.field, valid: func(v T) bool { return valid(convert(v)) }, key: r.key, args: r.args}
+	}
+	return lifted
+}
+
+// violation - нарушенное правило; текст переводится при записи ответа.
+type violation struct {
+	field string
+	key   i18n.Key
+	args  []interface{}
+}
+
+// validate проверяет value по наборам правил и возвращает все нарушения
+// сразу. Для поля учитывается только первое нарушенное правило, поэтому
+// обязательность и формат проверяются раньше диапазона.
+func validate[T any](value T, ruleSets ...[]rule[T]) []violation {
+	var violations []violation
+	failed := make(map[string]bool)
+	for _, rules := range ruleSets {
+		for _, rule := range rules {
+			if failed[rule.field] || rule.valid(value) {
+				continue
+			}
+			failed[rule.field] = true
+			violations = append(violations, violation{field: rule.field, key: rule.key, args: rule.args})
+		}
+	}
+	return violations
+}
+
+// writeViolations отвечает 422 со списком ошибок всех полей.
+func writeViolations(w http.ResponseWriter, r *http.Request, violations []violation) {
+	problem := newProblem(r, codeInvalidFields, i18n.InvalidFields)
+	for _, v := range violations {
+		problem.Errors = append(problem.Errors, FieldError{Field: v.field, Message: message(r, v.key, v.args...)})
+	}
+	writeProblem(w, problem)
+}
+
+// mergeViolations добавляет к нарушениям, найденным при разборе тела,
+// нарушения правил для остальных полей: поле, которое не удалось разобрать,
+// правилами не проверяется.
+func mergeViolations(decoded, checked []violation) []violation {
+	failed := make(map[string]bool, len(decoded))
+	for _, v := range decoded {
+		failed[v.field] = true
+	}
+	for _, v := range checked {
+		if !failed[v.field] {
+			decoded = append(decoded, v)
+		}
+	}
+	return decoded
+}
+
+// decodeBody читает JSON тела запроса в v. Если decodeBody вернул false,
+// ответ уже записан.
+func (f *Filmoteka) decodeBody(w http.ResponseWriter, r *http.Request, v interface{}, errorKey i18n.Key) ([]violation, bool) {
+	defer r.Body.Close()
+	return f.decodeJSON(w, r, r.Body, v, errorKey)
+}
+
+// decodeJSON читает JSON-объект из body в структуру, на которую указывает v.
+// Члены объекта разбираются по одному, чтобы ответ перечислил ошибки всех
+// полей сразу: неизвестное поле (опечатка в имени не должна терять значение
+// молча) и значение неверного типа или формата возвращаются как нарушения, к
+// которым вызывающий добавляет нарушения правил. Тело, которое не является
+// JSON-объектом, - invalid_body с сообщением errorKey; тогда decodeJSON
+// возвращает false и ответ уже записан.
+func (f *Filmoteka) decodeJSON(w http.ResponseWriter, r *http.Request, body io.Reader, v interface{}, errorKey i18n.Key) ([]violation, bool) {
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&members); err != nil {
+		f.Logger.Info("Response", slog.String("Body", err.Error()))
+		writeError(w, r, codeInvalidBody, errorKey)
+		return nil, false
+	}
+
+	var violations []violation
+	target := reflect.ValueOf(v).Elem()
+	for _, field := range jsonFields(target.Type()) {
+		member, ok := memberName(members, field.name)
+		if !ok {
+			continue
+		}
+		raw := members[member]
+		delete(members, member)
+
+		if err := json.Unmarshal(raw, target.Field(field.index).Addr().Interface()); err != nil {
+			f.Logger.Info("Response", slog.String("Body", err.Error()))
+			violations = append(violations, violation{field: field.name, key: decodeErrorKey(err)})
+		}
+	}
+
+	for _, member := range slices.Sorted(maps.Keys(members)) {
+		violations = append(violations, violation{field: member, key: i18n.UnknownField})
+	}
+	return violations, true
+}
+
+// jsonField - поле структуры, заполняемое из JSON: имя члена объекта и номер
+// поля в структуре.
+type jsonField struct {
+	name  string
+	index int
+}
+
+// jsonFields возвращает поля структуры t в порядке объявления с именами по
+// тегам json, как их видит encoding/json.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = field.Name
+		}
+		fields = append(fields, jsonField{name: name, index: i})
+	}
+	return fields
+}
+
+// memberName находит член объекта для поля name. Как и encoding/json, при
+// отсутствии точного совпадения имя сравнивается без учёта регистра.
+func memberName(members map[string]json.RawMessage, name string) (string, bool) {
+	if _, ok := members[name]; ok {
+		return name, true
+	}
+	for member := range members {
+		if strings.EqualFold(member, name) {
+			return member, true
+		}
+	}
+	return "", false
+}
+
+// decodeErrorKey выбирает сообщение для значения поля, которое не удалось
+// разобрать: дата в неверном формате или значение неверного типа.
+func decodeErrorKey(err error) i18n.Key {
+	var parseErr *time.ParseError
+	if errors.As(err, &parseErr) {
+		return i18n.InvalidDate
+	}
+	return i18n.InvalidFieldType
+}
@@ -0,0 +1,186 @@
+package i18n
+
+// en - каталог на английском языке.
+var en = map[Key]string{
+	InvalidBody:               "Cannot read the request body",
+	InvalidRequestJSON:        "Cannot decode the request",
+	UnknownField:              "Unknown field",
+	InvalidFieldType:          "Invalid value type",
+	InvalidFields:             "Some fields are invalid",
+	UnsupportedPatchType:      "PATCH body must be in one of the formats: %s",
+	UnsupportedPatchOperation: "JSON Patch operation %s is not supported",
+	InvalidPatchPath:          "JSON Patch path %s must point to a top-level field",
+	PatchValueRequired:        "JSON Patch operation %s requires a value",
+	IDMismatch:                "id in the body does not match the id in the path",
+	InvalidPage:               "Invalid pagination parameters",
+	InvalidFilter:             "Invalid filter parameters",
+	InvalidExpand:             "Invalid expand or fields parameters",
+	InvalidDate:               "Invalid date format",
+	SearchQueryRequired:       "Search query is required",
+	ActionRequiresAccount:     "This action is available to registered users only",
+	TokenMissing:              "Access token is missing",
+	TokenInvalid:              "Token is invalid or expired",
+	TokenCheckError:           "Error checking the token",
+	PermissionDenied:          "You do not have permission to perform this action",
+	RouteNotFound:             "No such API endpoint",
+	MethodNotAllowed:          "Method %s is not supported for this endpoint, allowed methods: %s",
+
+	ProblemInvalidBody:          "Malformed request body",
+	ProblemInvalidParameter:     "Invalid request parameter",
+	ProblemValidationFailed:     "Validation failed",
+	ProblemInvalidFields:        "Request fields failed validation",
+	ProblemUnauthorized:         "Authentication required",
+	ProblemInvalidCredentials:   "Invalid credentials",
+	ProblemForbidden:            "Forbidden",
+	ProblemNotFound:             "Resource not found",
+	ProblemMethodNotAllowed:     "Method not allowed",
+	ProblemUnsupportedMediaType: "Unsupported request body format",
+	ProblemConflict:             "Conflict with the current state of the resource",
+	ProblemInternal:             "Internal server error",
+
+	InvalidActorID:         "Invalid actor id",
+	ActorIDRequired:        "Actor id is required",
+	ActorNotFound:          "Actor not found",
+	ActorNameRequired:      "Actor name is required",
+	ActorNameTooLong:       "Actor name must be at most %d characters long",
+	InvalidGender:          "Gender must be one of: %s",
+	BirthdateOutOfRange:    "Birthdate must be no earlier than %s and not in the future",
+	ActorNameQueryRequired: "Actor name is not specified",
+	ActorDeleted:           "Actor deleted from the database",
+	ActorAddError:          "Error adding the actor to the database",
+	ActorGetError:          "Error getting the actor",
+	ActorUpdateError:       "Error updating the actor",
+	ActorDeleteError:       "Error deleting the actor",
+	ActorsGetError:         "Error getting actors",
+	ActorsSearchError:      "Error searching actors",
+	ActorMoviesGetError:    "Error getting the actor's movies",
+	CoStarsGetError:        "Error getting the actor's co-stars",
+	ActorPathNotFound:      "No connection between the actors was found",
+	ActorPathError:         "Error finding a connection between the actors",
+	InvalidMaxDepth:        "The max_depth parameter must be a number from 1 to %d",
+
+	InvalidMovieID:        "Invalid movie id",
+	MovieNotFound:         "Movie not found",
+	MovieIDRequired:       "Movie id is required",
+	MovieTitleRequired:    "Movie title is required",
+	ReleaseDateRequired:   "Movie release date is required",
+	InvalidReleaseDate:    "Invalid movie release date format",
+	ReleaseDateOutOfRange: "Movie release date must be no earlier than %s and at most %d years from today",
+	MovieTitleTooLong:     "Movie title must be at most %d characters long",
+	TitleOrActorRequired:  "Specify a title fragment or an actor name fragment",
+	MovieDeleted:          "Movie deleted from the database",
+	MovieAddError:         "Error adding the movie to the database",
+	MovieGetError:         "Error getting the movie",
+	MovieUpdateError:      "Error updating the movie",
+	MovieDeleteError:      "Error deleting the movie",
+	MoviesGetError:        "Error getting movies",
+	MoviesSearchError:     "Error searching movies",
+	MoviesByActorError:    "Error searching movies by actor name",
+	SuggestionsGetError:   "Error getting suggestions",
+
+	InvalidCastMember:    "Invalid actor id or billing position",
+	DuplicateCastMember:  "The actor is listed more than once",
+	CharacterTooLong:     "Character name is too long",
+	UnknownCreditType:    "Unknown credit type: allowed values are lead, supporting, cameo, voice",
+	CastMemberNotFound:   "Movie not found or the actor is not in its cast",
+	MovieOrActorNotFound: "Movie or actor not found",
+	ActorAlreadyInCast:   "The actor is already in the movie cast",
+	CastGetError:         "Error getting the movie cast",
+	CastUpdateError:      "Error updating the movie cast",
+	CastUpdated:          "Movie cast updated",
+	InvalidPersonID:      "Invalid person id",
+	PersonNotFound:       "Person not found",
+	DuplicateCrewMember:  "The person is listed more than once for the same job",
+	UnknownJob:           "Unknown job: allowed values are director, writer, producer, composer, cinematographer",
+	CrewNameRequired:     "Crew member name is not specified",
+	CrewMemberNotFound:   "Movie not found or the person is not in its crew",
+	CrewGetError:         "Error getting the movie crew",
+	CrewUpdateError:      "Error updating the movie crew",
+	MoviesByCrewError:    "Error searching movies by crew",
+
+	InvalidGenreID:         "Invalid genre id",
+	GenreNotFound:          "Genre not found",
+	GenreExists:            "A genre with this name already exists",
+	DuplicateGenre:         "The genre is listed more than once",
+	GenreNameRequired:      "Genre name is required",
+	GenreNameTooLong:       "Genre name is too long",
+	GenreDeleted:           "Genre deleted",
+	GenreAddError:          "Error adding the genre",
+	GenreGetError:          "Error getting the genre",
+	GenreUpdateError:       "Error updating the genre",
+	GenreDeleteError:       "Error deleting the genre",
+	GenresGetError:         "Error getting genres",
+	MovieGenresGetError:    "Error getting the movie genres",
+	MovieGenresUpdateError: "Error updating the movie genres",
+
+	InvalidReviewID:        "Invalid review id",
+	ReviewNotFound:         "Review not found",
+	ScoreRequired:          "Score is required",
+	InvalidScore:           "Score must be from 0 to 10",
+	UnknownReviewStatus:    "Unknown review status: allowed values are published, hidden",
+	HiddenReviewsForbidden: "Hidden reviews are available to moderators only",
+	ReviewDeleted:          "Review deleted",
+	ReviewGetError:         "Error getting the review",
+	ReviewsGetError:        "Error getting reviews",
+	ReviewSaveError:        "Error saving the review",
+	ReviewDeleteError:      "Error deleting the review",
+	ReviewStatusError:      "Error changing the review status",
+	RatingsGetError:        "Error getting the movie ratings",
+
+	WatchlistAdded:        "Movie added to the watchlist",
+	WatchlistExists:       "Movie is already in the watchlist",
+	WatchlistRemoved:      "Movie removed from the watchlist",
+	WatchlistItemNotFound: "Movie is not in the watchlist",
+	WatchlistGetError:     "Error getting the watchlist",
+	WatchlistAddError:     "Error adding the movie to the watchlist",
+	WatchlistRemoveError:  "Error removing the movie from the watchlist",
+	InvalidWatchedID:      "Invalid entry id",
+	WatchedNotFound:       "Entry not found",
+	InvalidWatchedOn:      "Invalid watch date format (expected YYYY.MM.DD)",
+	WatchedDeleted:        "Entry deleted",
+	WatchedGetError:       "Error getting the watch log",
+	WatchedAddError:       "Error adding an entry to the watch log",
+	WatchedDeleteError:    "Error deleting an entry from the watch log",
+	InvalidListID:         "Invalid list id",
+	ListNotFound:          "List or movie in it not found",
+	ListSlugExists:        "A list with this slug already exists",
+	ListNameRequired:      "List name is required",
+	ListColumnsTooLong:    "List name or slug is too long",
+	InvalidSlug:           "Slug must consist of lowercase latin letters, digits and hyphens and be at least 3 characters long",
+	ListDeleted:           "List deleted",
+	ListsGetError:         "Error getting lists",
+	ListGetError:          "Error getting the list",
+	ListCreateError:       "Error creating the list",
+	ListUpdateError:       "Error updating the list",
+	ListDeleteError:       "Error deleting the list",
+	ListMovieAddError:     "Error adding the movie to the list",
+	ListMovieRemoveError:  "Error removing the movie from the list",
+
+	SimilarMoviesGetError:       "Error getting similar movies",
+	RecommendationsGetError:     "Error getting recommendations",
+	RecommendationsRebuildError: "Error rebuilding recommendations",
+	StatsGetError:               "Error getting statistics",
+	InvalidStatsPeriod:          "The by parameter must be year or decade",
+	InvalidStatsLimit:           "The limit parameter must be an integer from 1 to %d",
+	InvalidRatingSource:         "The source parameter must be movies or reviews",
+
+	InvalidUserID:       "Invalid user id",
+	UserNotFound:        "User not found",
+	UserExists:          "A user with this name already exists",
+	InvalidUsername:     "Username is required and must not exceed 64 characters",
+	PasswordTooShort:    "Password must be at least 8 characters long",
+	PasswordTooLong:     "Password is too long",
+	InvalidCredentials:  "Invalid username or password",
+	UnknownRole:         "Unknown role",
+	TokenNotFound:       "Token is invalid",
+	UserRegistered:      "User registered",
+	RoleChanged:         "User role changed",
+	TokenRevoked:        "Token revoked",
+	UserTokensRevoked:   "User tokens revoked",
+	RegisterError:       "Error registering the user",
+	LoginError:          "Error signing in",
+	LogoutError:         "Error revoking the token",
+	RevokeTokensError:   "Error revoking the user's tokens",
+	SetRoleError:        "Error changing the user role",
+	PermissionsGetError: "Error getting token permissions",
+}
@@ -0,0 +1,89 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key - ключ сообщения в каталогах.
+type Key string
+
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// DefaultLanguage используется, если в конфигурации язык не задан.
+const DefaultLanguage = Russian
+
+var catalogs = map[string]map[Key]string{
+	Russian: ru,
+	English: en,
+}
+
+// Supported сообщает, есть ли каталог для языка.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Message возвращает сообщение key на языке lang, подставляя args по правилам
+// fmt. Если сообщения нет в каталоге lang, берётся каталог DefaultLanguage, а
+// если нет и там - сам ключ.
+func Message(lang string, key Key, args ...interface{}) string {
+	message, ok := catalogs[lang][key]
+	if !ok {
+		if message, ok = catalogs[DefaultLanguage][key]; !ok {
+			message = string(key)
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language (RFC 9110): из
+// поддерживаемых языков берётся язык с наибольшим весом q, при равных весах -
+// указанный раньше. Региональные варианты (en-US) сводятся к основному языку.
+// Если подходящего языка нет, возвращается fallback.
+func Negotiate(acceptLanguage, fallback string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if lang == "*" {
+			lang = fallback
+		}
+		if Supported(lang) {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	if len(candidates) == 0 {
+		return fallback
+	}
+	return candidates[0].lang
+}
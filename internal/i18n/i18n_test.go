@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// declaredKeys читает ключи, объявленные в keys.go, чтобы тест заметил и
+// ключ, который забыли добавить во все каталоги.
+func declaredKeys(t *testing.T) map[Key]string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "keys.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make(map[Key]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			for i, name := range value.Names {
+				literal, err := strconv.Unquote(value.Values[i].(*ast.BasicLit).Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if previous, ok := keys[Key(literal)]; ok {
+					t.Errorf("%s и %s используют один ключ %q", previous, name.Name, literal)
+				}
+				keys[Key(literal)] = name.Name
+			}
+		}
+	}
+	return keys
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	keys := declaredKeys(t)
+	if len(keys) == 0 {
+		t.Fatal("в keys.go не найдено ни одного ключа")
+	}
+
+	for lang, catalog := range catalogs {
+		for key, name := range keys {
+			if strings.TrimSpace(catalog[key]) == "" {
+				t.Errorf("в каталоге %q нет сообщения %s (%q)", lang, name, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := keys[key]; !ok {
+				t.Errorf("в каталоге %q есть сообщение с необъявленным ключом %q", lang, key)
+			}
+		}
+	}
+}
+
+// TestCatalogsVerbs проверяет, что переводы принимают те же аргументы.
+func TestCatalogsVerbs(t *testing.T) {
+	for key, message := range catalogs[DefaultLanguage] {
+		for lang, catalog := range catalogs {
+			if got, want := strings.Count(catalog[key], "%"), strings.Count(message, "%"); got != want {
+				t.Errorf("%q в каталоге %q: %d подстановок, в %q - %d", key, lang, got, DefaultLanguage, want)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", Russian},
+		{"en", English},
+		{"en-US,en;q=0.9", English},
+		{"de-DE,de;q=0.9", Russian},
+		{"de, en;q=0.5", English},
+		{"ru;q=0.3, en;q=0.8", English},
+		{"en;q=0, ru;q=0.1", Russian},
+		{"EN-gb", English},
+		{"*", Russian},
+		{"ru, en", Russian},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.header, Russian); got != test.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
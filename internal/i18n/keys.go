@@ -0,0 +1,216 @@
+package i18n
+
+// Ключи сообщений. Каждый ключ должен быть во всех каталогах; это проверяет
+// TestCatalogsComplete.
+
+// Общие ошибки запроса.
+const (
+	InvalidBody               Key = "invalid_body"
+	InvalidRequestJSON        Key = "invalid_request_json"
+	UnknownField              Key = "unknown_field"
+	InvalidFieldType          Key = "invalid_field_type"
+	InvalidFields             Key = "invalid_fields"
+	UnsupportedPatchType      Key = "unsupported_patch_type"
+	UnsupportedPatchOperation Key = "unsupported_patch_operation"
+	InvalidPatchPath          Key = "invalid_patch_path"
+	PatchValueRequired        Key = "patch_value_required"
+	IDMismatch                Key = "id_mismatch"
+	InvalidPage               Key = "invalid_page"
+	InvalidFilter             Key = "invalid_filter"
+	InvalidExpand             Key = "invalid_expand"
+	InvalidDate               Key = "invalid_date"
+	SearchQueryRequired       Key = "search_query_required"
+	ActionRequiresAccount     Key = "action_requires_account"
+	TokenMissing              Key = "token_missing"
+	TokenInvalid              Key = "token_invalid"
+	TokenCheckError           Key = "token_check_error"
+	PermissionDenied          Key = "permission_denied"
+	RouteNotFound             Key = "route_not_found"
+	MethodNotAllowed          Key = "method_not_allowed"
+)
+
+// Заголовки (title) ответов с ошибкой.
+const (
+	ProblemInvalidBody          Key = "problem_invalid_body"
+	ProblemInvalidParameter     Key = "problem_invalid_parameter"
+	ProblemValidationFailed     Key = "problem_validation_failed"
+	ProblemInvalidFields        Key = "problem_invalid_fields"
+	ProblemUnauthorized         Key = "problem_unauthorized"
+	ProblemInvalidCredentials   Key = "problem_invalid_credentials"
+	ProblemForbidden            Key = "problem_forbidden"
+	ProblemNotFound             Key = "problem_not_found"
+	ProblemMethodNotAllowed     Key = "problem_method_not_allowed"
+	ProblemUnsupportedMediaType Key = "problem_unsupported_media_type"
+	ProblemConflict             Key = "problem_conflict"
+	ProblemInternal             Key = "problem_internal"
+)
+
+// Актёры.
+const (
+	InvalidActorID         Key = "invalid_actor_id"
+	ActorIDRequired        Key = "actor_id_required"
+	ActorNotFound          Key = "actor_not_found"
+	ActorNameRequired      Key = "actor_name_required"
+	ActorNameTooLong       Key = "actor_name_too_long"
+	InvalidGender          Key = "invalid_gender"
+	BirthdateOutOfRange    Key = "birthdate_out_of_range"
+	ActorNameQueryRequired Key = "actor_name_query_required"
+	ActorDeleted           Key = "actor_deleted"
+	ActorAddError          Key = "actor_add_error"
+	ActorGetError          Key = "actor_get_error"
+	ActorUpdateError       Key = "actor_update_error"
+	ActorDeleteError       Key = "actor_delete_error"
+	ActorsGetError         Key = "actors_get_error"
+	ActorsSearchError      Key = "actors_search_error"
+	ActorMoviesGetError    Key = "actor_movies_get_error"
+	CoStarsGetError        Key = "costars_get_error"
+	ActorPathNotFound      Key = "actor_path_not_found"
+	ActorPathError         Key = "actor_path_error"
+	InvalidMaxDepth        Key = "invalid_max_depth"
+)
+
+// Фильмы.
+const (
+	InvalidMovieID        Key = "invalid_movie_id"
+	MovieNotFound         Key = "movie_not_found"
+	MovieIDRequired       Key = "movie_id_required"
+	MovieTitleRequired    Key = "movie_title_required"
+	ReleaseDateRequired   Key = "release_date_required"
+	InvalidReleaseDate    Key = "invalid_release_date"
+	ReleaseDateOutOfRange Key = "release_date_out_of_range"
+	MovieTitleTooLong     Key = "movie_title_too_long"
+	TitleOrActorRequired  Key = "title_or_actor_required"
+	MovieDeleted          Key = "movie_deleted"
+	MovieAddError         Key = "movie_add_error"
+	MovieGetError         Key = "movie_get_error"
+	MovieUpdateError      Key = "movie_update_error"
+	MovieDeleteError      Key = "movie_delete_error"
+	MoviesGetError        Key = "movies_get_error"
+	MoviesSearchError     Key = "movies_search_error"
+	MoviesByActorError    Key = "movies_by_actor_error"
+	SuggestionsGetError   Key = "suggestions_get_error"
+)
+
+// Состав и съёмочная группа.
+const (
+	InvalidCastMember    Key = "invalid_cast_member"
+	DuplicateCastMember  Key = "duplicate_cast_member"
+	CharacterTooLong     Key = "character_too_long"
+	UnknownCreditType    Key = "unknown_credit_type"
+	CastMemberNotFound   Key = "cast_member_not_found"
+	MovieOrActorNotFound Key = "movie_or_actor_not_found"
+	ActorAlreadyInCast   Key = "actor_already_in_cast"
+	CastGetError         Key = "cast_get_error"
+	CastUpdateError      Key = "cast_update_error"
+	CastUpdated          Key = "cast_updated"
+	InvalidPersonID      Key = "invalid_person_id"
+	PersonNotFound       Key = "person_not_found"
+	DuplicateCrewMember  Key = "duplicate_crew_member"
+	UnknownJob           Key = "unknown_job"
+	CrewNameRequired     Key = "crew_name_required"
+	CrewMemberNotFound   Key = "crew_member_not_found"
+	CrewGetError         Key = "crew_get_error"
+	CrewUpdateError      Key = "crew_update_error"
+	MoviesByCrewError    Key = "movies_by_crew_error"
+)
+
+// Жанры.
+const (
+	InvalidGenreID         Key = "invalid_genre_id"
+	GenreNotFound          Key = "genre_not_found"
+	GenreExists            Key = "genre_exists"
+	DuplicateGenre         Key = "duplicate_genre"
+	GenreNameRequired      Key = "genre_name_required"
+	GenreNameTooLong       Key = "genre_name_too_long"
+	GenreDeleted           Key = "genre_deleted"
+	GenreAddError          Key = "genre_add_error"
+	GenreGetError          Key = "genre_get_error"
+	GenreUpdateError       Key = "genre_update_error"
+	GenreDeleteError       Key = "genre_delete_error"
+	GenresGetError         Key = "genres_get_error"
+	MovieGenresGetError    Key = "movie_genres_get_error"
+	MovieGenresUpdateError Key = "movie_genres_update_error"
+)
+
+// Отзывы.
+const (
+	InvalidReviewID        Key = "invalid_review_id"
+	ReviewNotFound         Key = "review_not_found"
+	ScoreRequired          Key = "score_required"
+	InvalidScore           Key = "invalid_score"
+	UnknownReviewStatus    Key = "unknown_review_status"
+	HiddenReviewsForbidden Key = "hidden_reviews_forbidden"
+	ReviewDeleted          Key = "review_deleted"
+	ReviewGetError         Key = "review_get_error"
+	ReviewsGetError        Key = "reviews_get_error"
+	ReviewSaveError        Key = "review_save_error"
+	ReviewDeleteError      Key = "review_delete_error"
+	ReviewStatusError      Key = "review_status_error"
+	RatingsGetError        Key = "ratings_get_error"
+)
+
+// Личные списки.
+const (
+	WatchlistAdded        Key = "watchlist_added"
+	WatchlistExists       Key = "watchlist_exists"
+	WatchlistRemoved      Key = "watchlist_removed"
+	WatchlistItemNotFound Key = "watchlist_item_not_found"
+	WatchlistGetError     Key = "watchlist_get_error"
+	WatchlistAddError     Key = "watchlist_add_error"
+	WatchlistRemoveError  Key = "watchlist_remove_error"
+	InvalidWatchedID      Key = "invalid_watched_id"
+	WatchedNotFound       Key = "watched_not_found"
+	InvalidWatchedOn      Key = "invalid_watched_on"
+	WatchedDeleted        Key = "watched_deleted"
+	WatchedGetError       Key = "watched_get_error"
+	WatchedAddError       Key = "watched_add_error"
+	WatchedDeleteError    Key = "watched_delete_error"
+	InvalidListID         Key = "invalid_list_id"
+	ListNotFound          Key = "list_not_found"
+	ListSlugExists        Key = "list_slug_exists"
+	ListNameRequired      Key = "list_name_required"
+	ListColumnsTooLong    Key = "list_columns_too_long"
+	InvalidSlug           Key = "invalid_slug"
+	ListDeleted           Key = "list_deleted"
+	ListsGetError         Key = "lists_get_error"
+	ListGetError          Key = "list_get_error"
+	ListCreateError       Key = "list_create_error"
+	ListUpdateError       Key = "list_update_error"
+	ListDeleteError       Key = "list_delete_error"
+	ListMovieAddError     Key = "list_movie_add_error"
+	ListMovieRemoveError  Key = "list_movie_remove_error"
+)
+
+// Рекомендации и статистика.
+const (
+	SimilarMoviesGetError       Key = "similar_movies_get_error"
+	RecommendationsGetError     Key = "recommendations_get_error"
+	RecommendationsRebuildError Key = "recommendations_rebuild_error"
+	StatsGetError               Key = "stats_get_error"
+	InvalidStatsPeriod          Key = "invalid_stats_period"
+	InvalidStatsLimit           Key = "invalid_stats_limit"
+	InvalidRatingSource         Key = "invalid_rating_source"
+)
+
+// Пользователи и токены.
+const (
+	InvalidUserID       Key = "invalid_user_id"
+	UserNotFound        Key = "user_not_found"
+	UserExists          Key = "user_exists"
+	InvalidUsername     Key = "invalid_username"
+	PasswordTooShort    Key = "password_too_short"
+	PasswordTooLong     Key = "password_too_long"
+	InvalidCredentials  Key = "invalid_credentials"
+	UnknownRole         Key = "unknown_role"
+	TokenNotFound       Key = "token_not_found"
+	UserRegistered      Key = "user_registered"
+	RoleChanged         Key = "role_changed"
+	TokenRevoked        Key = "token_revoked"
+	UserTokensRevoked   Key = "user_tokens_revoked"
+	RegisterError       Key = "register_error"
+	LoginError          Key = "login_error"
+	LogoutError         Key = "logout_error"
+	RevokeTokensError   Key = "revoke_tokens_error"
+	SetRoleError        Key = "set_role_error"
+	PermissionsGetError Key = "permissions_get_error"
+)
@@ -0,0 +1,186 @@
+package i18n
+
+// ru - каталог на русском языке, языке по умолчанию.
+var ru = map[Key]string{
+	InvalidBody:               "Невозможно прочитать тело запроса",
+	InvalidRequestJSON:        "Ошибка при декодировании запроса",
+	UnknownField:              "Неизвестное поле",
+	InvalidFieldType:          "Неверный тип значения",
+	InvalidFields:             "Некоторые поля заполнены неверно",
+	UnsupportedPatchType:      "Тело PATCH должно быть в одном из форматов: %s",
+	UnsupportedPatchOperation: "Операция JSON Patch %s не поддерживается",
+	InvalidPatchPath:          "Путь JSON Patch %s должен указывать на поле верхнего уровня",
+	PatchValueRequired:        "Для операции JSON Patch %s нужно значение value",
+	IDMismatch:                "id в теле не совпадает с id в пути запроса",
+	InvalidPage:               "Неверные параметры пагинации",
+	InvalidFilter:             "Неверные параметры фильтра",
+	InvalidExpand:             "Неверные параметры expand или fields",
+	InvalidDate:               "Неверный формат даты",
+	SearchQueryRequired:       "Не указана строка поиска",
+	ActionRequiresAccount:     "Действие доступно только зарегистрированным пользователям",
+	TokenMissing:              "Не передан токен доступа",
+	TokenInvalid:              "Токен недействителен или истёк",
+	TokenCheckError:           "Ошибка при проверке токена",
+	PermissionDenied:          "Недостаточно прав для выполнения действия",
+	RouteNotFound:             "Такого адреса в API нет",
+	MethodNotAllowed:          "Метод %s не поддерживается для этого адреса, допустимые методы: %s",
+
+	ProblemInvalidBody:          "Некорректное тело запроса",
+	ProblemInvalidParameter:     "Некорректный параметр запроса",
+	ProblemValidationFailed:     "Ошибка проверки данных",
+	ProblemInvalidFields:        "Поля запроса не прошли проверку",
+	ProblemUnauthorized:         "Требуется аутентификация",
+	ProblemInvalidCredentials:   "Неверные учётные данные",
+	ProblemForbidden:            "Доступ запрещён",
+	ProblemNotFound:             "Ресурс не найден",
+	ProblemMethodNotAllowed:     "Метод не поддерживается",
+	ProblemUnsupportedMediaType: "Неподдерживаемый формат тела запроса",
+	ProblemConflict:             "Конфликт с текущим состоянием ресурса",
+	ProblemInternal:             "Внутренняя ошибка сервера",
+
+	InvalidActorID:         "Неверный идентификатор актера",
+	ActorIDRequired:        "Идентификатор актера обязателен для обновления",
+	ActorNotFound:          "Актер не найден",
+	ActorNameRequired:      "Имя актера обязательно для заполнения",
+	ActorNameTooLong:       "Имя актера не должно быть длиннее %d символов",
+	InvalidGender:          "Пол должен быть одним из значений: %s",
+	BirthdateOutOfRange:    "Дата рождения должна быть не раньше %s и не позже сегодняшнего дня",
+	ActorNameQueryRequired: "Не указано имя актера",
+	ActorDeleted:           "Актер успешно удален из базы данных",
+	ActorAddError:          "Ошибка при добавлении актера в базу данных",
+	ActorGetError:          "Ошибка при получении актера",
+	ActorUpdateError:       "Ошибка при обновлении актера",
+	ActorDeleteError:       "Ошибка при удалении актера",
+	ActorsGetError:         "Ошибка при получении списка актёров",
+	ActorsSearchError:      "Ошибка при поиске актёров",
+	ActorMoviesGetError:    "Ошибка при получении списка фильмов актёра",
+	CoStarsGetError:        "Ошибка при получении партнёров актёра",
+	ActorPathNotFound:      "Связь между актёрами не найдена",
+	ActorPathError:         "Ошибка при поиске связи между актёрами",
+	InvalidMaxDepth:        "Параметр max_depth должен быть числом от 1 до %d",
+
+	InvalidMovieID:        "Неверный идентификатор фильма",
+	MovieNotFound:         "Фильм не найден",
+	MovieIDRequired:       "Идентификатор фильма обязателен для обновления",
+	MovieTitleRequired:    "Название фильма обязательно для заполнения",
+	ReleaseDateRequired:   "Дата выхода фильма обязательна для заполнения",
+	InvalidReleaseDate:    "Неверный формат даты выхода фильма",
+	ReleaseDateOutOfRange: "Дата выхода фильма должна быть не раньше %s и не позже чем через %d лет от сегодняшнего дня",
+	MovieTitleTooLong:     "Название фильма не должно быть длиннее %d символов",
+	TitleOrActorRequired:  "Не указан фрагмент названия или фрагмент имени актёра",
+	MovieDeleted:          "Фильм успешно удален из базы данных",
+	MovieAddError:         "Ошибка при добавлении фильма в базу данных",
+	MovieGetError:         "Ошибка при получении фильма",
+	MovieUpdateError:      "Ошибка при обновлении информации о фильме",
+	MovieDeleteError:      "Ошибка при удалении фильма",
+	MoviesGetError:        "Ошибка при получении списка фильмов",
+	MoviesSearchError:     "Ошибка при поиске фильмов",
+	MoviesByActorError:    "Ошибка при поиске фильмов по имени актера",
+	SuggestionsGetError:   "Ошибка при получении подсказок",
+
+	InvalidCastMember:    "Неверный идентификатор актера или место в титрах",
+	DuplicateCastMember:  "Актер указан в списке несколько раз",
+	CharacterTooLong:     "Имя персонажа слишком длинное",
+	UnknownCreditType:    "Неизвестный тип участия: допустимы lead, supporting, cameo, voice",
+	CastMemberNotFound:   "Фильм не найден или актер не входит в его состав",
+	MovieOrActorNotFound: "Фильм или актер не найден",
+	ActorAlreadyInCast:   "Актер уже входит в состав фильма",
+	CastGetError:         "Ошибка при получении состава фильма",
+	CastUpdateError:      "Ошибка при обновлении списка актеров для фильма",
+	CastUpdated:          "Список актеров для фильма успешно обновлен",
+	InvalidPersonID:      "Неверный идентификатор человека",
+	PersonNotFound:       "Человек не найден",
+	DuplicateCrewMember:  "Человек указан на одной должности несколько раз",
+	UnknownJob:           "Неизвестная должность: допустимы director, writer, producer, composer, cinematographer",
+	CrewNameRequired:     "Не указано имя человека из съёмочной группы",
+	CrewMemberNotFound:   "Фильм не найден или человек не входит в его съёмочную группу",
+	CrewGetError:         "Ошибка при получении съёмочной группы фильма",
+	CrewUpdateError:      "Ошибка при обновлении съёмочной группы фильма",
+	MoviesByCrewError:    "Ошибка при поиске фильмов по съёмочной группе",
+
+	InvalidGenreID:         "Неверный идентификатор жанра",
+	GenreNotFound:          "Жанр не найден",
+	GenreExists:            "Жанр с таким названием уже существует",
+	DuplicateGenre:         "Жанр указан в списке несколько раз",
+	GenreNameRequired:      "Название жанра обязательно для заполнения",
+	GenreNameTooLong:       "Название жанра слишком длинное",
+	GenreDeleted:           "Жанр успешно удален",
+	GenreAddError:          "Ошибка при добавлении жанра",
+	GenreGetError:          "Ошибка при получении жанра",
+	GenreUpdateError:       "Ошибка при обновлении жанра",
+	GenreDeleteError:       "Ошибка при удалении жанра",
+	GenresGetError:         "Ошибка при получении списка жанров",
+	MovieGenresGetError:    "Ошибка при получении жанров фильма",
+	MovieGenresUpdateError: "Ошибка при обновлении жанров фильма",
+
+	InvalidReviewID:        "Неверный идентификатор отзыва",
+	ReviewNotFound:         "Отзыв не найден",
+	ScoreRequired:          "Оценка обязательна для заполнения",
+	InvalidScore:           "Оценка должна быть от 0 до 10",
+	UnknownReviewStatus:    "Неизвестный статус отзыва: допустимы published, hidden",
+	HiddenReviewsForbidden: "Скрытые отзывы доступны только модераторам",
+	ReviewDeleted:          "Отзыв успешно удален",
+	ReviewGetError:         "Ошибка при получении отзыва",
+	ReviewsGetError:        "Ошибка при получении отзывов",
+	ReviewSaveError:        "Ошибка при сохранении отзыва",
+	ReviewDeleteError:      "Ошибка при удалении отзыва",
+	ReviewStatusError:      "Ошибка при изменении статуса отзыва",
+	RatingsGetError:        "Ошибка при получении оценок фильма",
+
+	WatchlistAdded:        "Фильм добавлен в список «Посмотреть позже»",
+	WatchlistExists:       "Фильм уже есть в списке «Посмотреть позже»",
+	WatchlistRemoved:      "Фильм удален из списка «Посмотреть позже»",
+	WatchlistItemNotFound: "Фильма нет в списке «Посмотреть позже»",
+	WatchlistGetError:     "Ошибка при получении списка «Посмотреть позже»",
+	WatchlistAddError:     "Ошибка при добавлении фильма в список «Посмотреть позже»",
+	WatchlistRemoveError:  "Ошибка при удалении фильма из списка «Посмотреть позже»",
+	InvalidWatchedID:      "Неверный идентификатор записи",
+	WatchedNotFound:       "Запись не найдена",
+	InvalidWatchedOn:      "Неверный формат даты просмотра (ожидается ГГГГ.ММ.ДД)",
+	WatchedDeleted:        "Запись успешно удалена",
+	WatchedGetError:       "Ошибка при получении журнала просмотров",
+	WatchedAddError:       "Ошибка при добавлении записи в журнал просмотров",
+	WatchedDeleteError:    "Ошибка при удалении записи из журнала просмотров",
+	InvalidListID:         "Неверный идентификатор списка",
+	ListNotFound:          "Список или фильм в нём не найден",
+	ListSlugExists:        "Список с таким slug уже существует",
+	ListNameRequired:      "Название списка обязательно для заполнения",
+	ListColumnsTooLong:    "Название списка или slug слишком длинные",
+	InvalidSlug:           "Slug должен состоять из латинских букв в нижнем регистре, цифр и дефисов и быть не короче 3 символов",
+	ListDeleted:           "Список успешно удален",
+	ListsGetError:         "Ошибка при получении списков",
+	ListGetError:          "Ошибка при получении списка",
+	ListCreateError:       "Ошибка при создании списка",
+	ListUpdateError:       "Ошибка при обновлении списка",
+	ListDeleteError:       "Ошибка при удалении списка",
+	ListMovieAddError:     "Ошибка при добавлении фильма в список",
+	ListMovieRemoveError:  "Ошибка при удалении фильма из списка",
+
+	SimilarMoviesGetError:       "Ошибка при получении похожих фильмов",
+	RecommendationsGetError:     "Ошибка при получении рекомендаций",
+	RecommendationsRebuildError: "Ошибка при пересчёте рекомендаций",
+	StatsGetError:               "Ошибка при получении статистики",
+	InvalidStatsPeriod:          "Параметр by должен быть year или decade",
+	InvalidStatsLimit:           "Параметр limit должен быть целым числом от 1 до %d",
+	InvalidRatingSource:         "Параметр source должен быть movies или reviews",
+
+	InvalidUserID:       "Неверный идентификатор пользователя",
+	UserNotFound:        "Пользователь не найден",
+	UserExists:          "Пользователь с таким именем уже существует",
+	InvalidUsername:     "Имя пользователя обязательно и не должно превышать 64 символа",
+	PasswordTooShort:    "Пароль должен содержать не менее 8 символов",
+	PasswordTooLong:     "Пароль слишком длинный",
+	InvalidCredentials:  "Неверное имя пользователя или пароль",
+	UnknownRole:         "Неизвестная роль",
+	TokenNotFound:       "Токен недействителен",
+	UserRegistered:      "Пользователь успешно зарегистрирован",
+	RoleChanged:         "Роль пользователя успешно изменена",
+	TokenRevoked:        "Токен успешно отозван",
+	UserTokensRevoked:   "Токены пользователя успешно отозваны",
+	RegisterError:       "Ошибка при регистрации пользователя",
+	LoginError:          "Ошибка при входе в систему",
+	LogoutError:         "Ошибка при отзыве токена",
+	RevokeTokensError:   "Ошибка при отзыве токенов пользователя",
+	SetRoleError:        "Ошибка при изменении роли пользователя",
+	PermissionsGetError: "Ошибка при получении прав токена",
+}
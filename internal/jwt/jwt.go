@@ -0,0 +1,294 @@
+package jwt
+
+import (
+	"TestVK/internal/config"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrUnknownKey       = errors.New("no matching verification key")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrExpired          = errors.New("token is expired")
+	ErrNotYetValid      = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+)
+
+var hashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256,
+	"HS384": crypto.SHA384,
+	"HS512": crypto.SHA512,
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Claims - проверенное содержимое токена.
+type Claims map[string]any
+
+func (c Claims) Subject() string {
+	subject, _ := c["sub"].(string)
+	return subject
+}
+
+// String возвращает строковое значение claim. Для массива берётся первый элемент.
+func (c Claims) String(name string) string {
+	switch value := c[name].(type) {
+	case string:
+		return value
+	case []any:
+		if len(value) > 0 {
+			first, _ := value[0].(string)
+			return first
+		}
+	}
+	return ""
+}
+
+func (c Claims) numericDate(name string) (time.Time, bool, error) {
+	raw, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	number, ok := raw.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %s is not a number", ErrMalformed, name)
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %s: %v", ErrMalformed, name, err)
+	}
+
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*1e9)), true, nil
+}
+
+func (c Claims) audience() []string {
+	switch value := c["aud"].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		audience := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+		return audience
+	}
+	return nil
+}
+
+// Verifier проверяет подпись и стандартные claims токенов. Одновременно может
+// быть активно несколько ключей: нужный выбирается по заголовку kid, что
+// позволяет ротировать ключи без простоя.
+type Verifier struct {
+	cfg config.JWTConfig
+	now func() time.Time
+
+	mu   sync.RWMutex
+	keys []Key
+}
+
+func NewVerifier(cfg config.JWTConfig) (*Verifier, error) {
+	keys, err := loadKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		cfg:  cfg,
+		now:  time.Now,
+		keys: keys,
+	}, nil
+}
+
+// Reload перечитывает ключи из конфигурации и JWKS-файла. При ошибке
+// продолжают действовать ранее загруженные ключи.
+func (v *Verifier) Reload() error {
+	keys, err := loadKeys(v.cfg)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.mu.Unlock()
+
+	return nil
+}
+
+// LooksLikeJWT отличает JWT от непрозрачных токенов по количеству сегментов.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+
+	keys := v.candidates(h)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: kid %q, alg %q", ErrUnknownKey, h.KeyID, h.Algorithm)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verify(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidSignature
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// candidates возвращает ключи, которыми можно проверить токен. Алгоритм ключа
+// должен совпадать с заголовком alg, чтобы исключить подмену алгоритма.
+func (v *Verifier) candidates(h header) []Key {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var keys []Key
+	for _, key := range v.keys {
+		if key.Algorithm != h.Algorithm {
+			continue
+		}
+		if h.KeyID != "" && key.ID != h.KeyID {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (v *Verifier) validate(claims Claims) error {
+	now := v.now()
+	leeway := v.cfg.Leeway
+
+	expiresAt, ok, err := claims.numericDate("exp")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: exp is required", ErrMalformed)
+	}
+	if !now.Before(expiresAt.Add(leeway)) {
+		return ErrExpired
+	}
+
+	notBefore, ok, err := claims.numericDate("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(leeway).Before(notBefore) {
+		return ErrNotYetValid
+	}
+
+	if v.cfg.Issuer != "" {
+		if issuer, _ := claims["iss"].(string); issuer != v.cfg.Issuer {
+			return ErrInvalidIssuer
+		}
+	}
+
+	if v.cfg.Audience != "" {
+		found := false
+		for _, audience := range claims.audience() {
+			if audience == v.cfg.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+func verify(key Key, signed, signature []byte) bool {
+	if key.Algorithm == "EdDSA" {
+		publicKey, ok := key.PublicKey.(ed25519.PublicKey)
+		return ok && ed25519.Verify(publicKey, signed, signature)
+	}
+
+	hash, ok := hashes[key.Algorithm]
+	if !ok {
+		return false
+	}
+
+	if strings.HasPrefix(key.Algorithm, "HS") {
+		mac := hmac.New(hash.New, key.Secret)
+		mac.Write(signed)
+		return hmac.Equal(signature, mac.Sum(nil))
+	}
+
+	publicKey, ok := key.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return false
+	}
+	digest := hash.New()
+	digest.Write(signed)
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest.Sum(nil), signature) == nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package jwt
+
+import (
+	"TestVK/internal/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// now - момент проверки во всех тестах; Verifier.now подменяется на него.
+var now = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// fixtureSecret и fixtureSeed соответствуют ключам oct-1 и ed-1 из
+// testdata/jwks.json, testdata/rsa.pem - ключу rsa-1.
+var (
+	fixtureSecret = []byte("fixture-hmac-secret-0123456789abcdef")
+	fixtureSeed   = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
+		17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32}
+)
+
+// signer подписывает токены одним ключом.
+type signer struct {
+	alg  string
+	kid  string
+	sign func(signed []byte) []byte
+}
+
+func hmacSigner(alg, kid string, secret []byte) signer {
+	return signer{alg: alg, kid: kid, sign: func(signed []byte) []byte {
+		mac := hmac.New(hashes[alg].New, secret)
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}}
+}
+
+func rsaSigner(t *testing.T, alg, kid string, key *rsa.PrivateKey) signer {
+	return signer{alg: alg, kid: kid, sign: func(signed []byte) []byte {
+		digest := hashes[alg].New()
+		digest.Write(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, hashes[alg], digest.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}}
+}
+
+func edSigner(kid string, key ed25519.PrivateKey) signer {
+	return signer{alg: "EdDSA", kid: kid, sign: func(signed []byte) []byte {
+		return ed25519.Sign(key, signed)
+	}}
+}
+
+func (s signer) token(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	h := map[string]string{"alg": s.alg, "typ": "JWT"}
+	if s.kid != "" {
+		h["kid"] = s.kid
+	}
+	signed := segment(t, h) + "." + segment(t, claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(s.sign([]byte(signed)))
+}
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// validClaims - claims, которые проходят проверку verifier с testConfig.
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub": "42",
+		"iss": "filmoteka",
+		"aud": "filmoteka-api",
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func testConfig(keys ...config.JWTKey) config.JWTConfig {
+	return config.JWTConfig{
+		Issuer:   "filmoteka",
+		Audience: "filmoteka-api",
+		Leeway:   30 * time.Second,
+		Keys:     keys,
+	}
+}
+
+func newVerifier(t *testing.T, cfg config.JWTConfig) *Verifier {
+	t.Helper()
+
+	verifier, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier.now = func() time.Time { return now }
+	return verifier
+}
+
+// writePublicKey сохраняет открытый ключ в PEM для JWTKey.PublicKeyFile.
+func writePublicKey(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func fixtureRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "rsa.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("testdata/rsa.pem: нет PEM-блока")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifySignatures(t *testing.T) {
+	rsaKey := fixtureRSAKey(t)
+	edKey := ed25519.NewKeyFromSeed(fixtureSeed)
+
+	verifier := newVerifier(t, testConfig(
+		config.JWTKey{ID: "hs", Algorithm: "HS256", Secret: string(fixtureSecret)},
+		config.JWTKey{ID: "rs", Algorithm: "RS256", PublicKeyFile: writePublicKey(t, &rsaKey.PublicKey)},
+		config.JWTKey{ID: "ed", Algorithm: "EdDSA", PublicKeyFile: writePublicKey(t, edKey.Public())},
+	))
+
+	signers := []signer{
+		hmacSigner("HS256", "hs", fixtureSecret),
+		rsaSigner(t, "RS256", "rs", rsaKey),
+		edSigner("ed", edKey),
+	}
+	for _, s := range signers {
+		t.Run(s.alg, func(t *testing.T) {
+			token := s.token(t, validClaims())
+
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.Subject() != "42" {
+				t.Errorf("sub = %q, ожидалось %q", claims.Subject(), "42")
+			}
+
+			tampered := token[:len(token)-4] + "AAAA"
+			if _, err := verifier.Verify(tampered); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("изменённая подпись: ошибка %v, ожидалась %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUnknownKey(t *testing.T) {
+	rsaKey := fixtureRSAKey(t)
+	verifier := newVerifier(t, testConfig(
+		config.JWTKey{ID: "rs", Algorithm: "RS256", PublicKeyFile: writePublicKey(t, &rsaKey.PublicKey)},
+	))
+
+	tests := []struct {
+		name   string
+		signer signer
+	}{
+		// Подмена алгоритма: открытый RSA-ключ как секрет HMAC.
+		{"alg не совпадает с ключом", hmacSigner("HS256", "rs", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))},
+		{"alg none", signer{alg: "none", kid: "rs", sign: func([]byte) []byte { return nil }}},
+		{"неизвестный kid", rsaSigner(t, "RS256", "rs-old", rsaKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.Verify(tt.signer.token(t, validClaims())); !errors.Is(err, ErrUnknownKey) {
+				t.Errorf("ошибка %v, ожидалась %v", err, ErrUnknownKey)
+			}
+		})
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	s := hmacSigner("HS256", "hs", fixtureSecret)
+	verifier := newVerifier(t, testConfig(config.JWTKey{ID: "hs", Algorithm: "HS256", Secret: string(fixtureSecret)}))
+
+	tests := []struct {
+		name   string
+		change func(claims map[string]any)
+		want   error
+	}{
+		{"все claims верны", func(map[string]any) {}, nil},
+		{"нет exp", func(c map[string]any) { delete(c, "exp") }, ErrMalformed},
+		{"exp не число", func(c map[string]any) { c["exp"] = "tomorrow" }, ErrMalformed},
+		{"истёк", func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() }, ErrExpired},
+		{"истёк в пределах leeway", func(c map[string]any) { c["exp"] = now.Add(-10 * time.Second).Unix() }, nil},
+		{"nbf в будущем", func(c map[string]any) { c["nbf"] = now.Add(time.Minute).Unix() }, ErrNotYetValid},
+		{"nbf в пределах leeway", func(c map[string]any) { c["nbf"] = now.Add(10 * time.Second).Unix() }, nil},
+		{"чужой iss", func(c map[string]any) { c["iss"] = "other" }, ErrInvalidIssuer},
+		{"нет iss", func(c map[string]any) { delete(c, "iss") }, ErrInvalidIssuer},
+		{"чужой aud", func(c map[string]any) { c["aud"] = "other-api" }, ErrInvalidAudience},
+		{"aud массивом", func(c map[string]any) { c["aud"] = []string{"other-api", "filmoteka-api"} }, nil},
+		{"aud массивом без нашего", func(c map[string]any) { c["aud"] = []string{"other-api"} }, ErrInvalidAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.change(claims)
+
+			_, err := verifier.Verify(s.token(t, claims))
+			if tt.want == nil && err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestVerifyKeyRotation проверяет, что во время ротации принимаются токены,
+// подписанные и старым, и новым ключом.
+func TestVerifyKeyRotation(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret-0123456789abcdef012345"), []byte("new-secret-0123456789abcdef012345")
+	verifier := newVerifier(t, testConfig(
+		config.JWTKey{ID: "2024-05", Algorithm: "HS256", Secret: string(oldSecret)},
+		config.JWTKey{ID: "2024-06", Algorithm: "HS256", Secret: string(newSecret)},
+	))
+
+	tests := []struct {
+		name   string
+		signer signer
+		want   error
+	}{
+		{"старый ключ", hmacSigner("HS256", "2024-05", oldSecret), nil},
+		{"новый ключ", hmacSigner("HS256", "2024-06", newSecret), nil},
+		{"без kid", hmacSigner("HS256", "", newSecret), nil},
+		{"kid другого ключа", hmacSigner("HS256", "2024-05", newSecret), ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(tt.signer.token(t, validClaims()))
+			if tt.want == nil && err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	keys, err := loadJWKS(filepath.Join("testdata", "jwks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ключи без alg получают алгоритм по типу, ключ шифрования пропускается.
+	want := map[string]string{"oct-1": "HS256", "rsa-1": "RS256", "ed-1": "EdDSA"}
+	if len(keys) != len(want) {
+		t.Errorf("загружено %d ключей, ожидалось %d", len(keys), len(want))
+	}
+	for _, key := range keys {
+		if want[key.ID] != key.Algorithm {
+			t.Errorf("ключ %q: алгоритм %q, ожидался %q", key.ID, key.Algorithm, want[key.ID])
+		}
+	}
+
+	cfg := testConfig()
+	cfg.JWKSFile = filepath.Join("testdata", "jwks.json")
+	verifier := newVerifier(t, cfg)
+
+	signers := []signer{
+		hmacSigner("HS256", "oct-1", fixtureSecret),
+		rsaSigner(t, "RS256", "rsa-1", fixtureRSAKey(t)),
+		edSigner("ed-1", ed25519.NewKeyFromSeed(fixtureSeed)),
+	}
+	for _, s := range signers {
+		if _, err := verifier.Verify(s.token(t, validClaims())); err != nil {
+			t.Errorf("%s (%s): %v", s.kid, s.alg, err)
+		}
+	}
+}
+
+func TestLoadJWKSRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  map[string]string
+		want error
+	}{
+		{"короткий RSA-ключ", map[string]string{"kty": "RSA", "kid": "rsa", "n": "AQAB", "e": "AQAB"}, ErrInvalidKey},
+		{"чужая кривая", map[string]string{"kty": "OKP", "kid": "x", "crv": "X25519", "x": "AQAB"}, ErrUnsupportedAlgorithm},
+		{"неизвестный тип", map[string]string{"kty": "EC", "kid": "ec", "crv": "P-256"}, ErrUnsupportedAlgorithm},
+		{"alg не подходит к ключу", map[string]string{"kty": "oct", "kid": "oct", "alg": "RS256", "k": "c2VjcmV0"}, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(map[string]any{"keys": []map[string]string{tt.key}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := loadJWKS(path); !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+}
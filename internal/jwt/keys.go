@@ -0,0 +1,224 @@
+package jwt
+
+import (
+	"TestVK/internal/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidKey           = errors.New("invalid verification key")
+)
+
+// Key - ключ проверки подписи. Для HMAC заполняется Secret, для RSA и Ed25519 - PublicKey.
+type Key struct {
+	ID        string
+	Algorithm string
+	Secret    []byte
+	PublicKey crypto.PublicKey
+}
+
+// jwkAlgorithms - алгоритм ключа JWKS без alg. В RFC 7517 alg необязателен,
+// поэтому алгоритм выводится из типа ключа (для OKP кривая проверяется отдельно).
+var jwkAlgorithms = map[string]string{
+	"oct": "HS256",
+	"RSA": "RS256",
+	"OKP": "EdDSA",
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+}
+
+// loadKeys собирает ключи из конфигурации и локального JWKS-файла.
+func loadKeys(cfg config.JWTConfig) ([]Key, error) {
+	var keys []Key
+	for _, keyConfig := range cfg.Keys {
+		key, err := keyFromConfig(keyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyConfig.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if cfg.JWKSFile != "" {
+		jwksKeys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwksKeys...)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys configured", ErrInvalidKey)
+	}
+
+	return keys, nil
+}
+
+func keyFromConfig(cfg config.JWTKey) (Key, error) {
+	key := Key{ID: cfg.ID, Algorithm: cfg.Algorithm}
+
+	switch {
+	case strings.HasPrefix(cfg.Algorithm, "HS"):
+		if cfg.Secret == "" {
+			return Key{}, fmt.Errorf("%w: secret is required for %s", ErrInvalidKey, cfg.Algorithm)
+		}
+		key.Secret = []byte(cfg.Secret)
+	case strings.HasPrefix(cfg.Algorithm, "RS"), cfg.Algorithm == "EdDSA":
+		publicKey, err := readPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return Key{}, err
+		}
+		key.PublicKey = publicKey
+	default:
+		return Key{}, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+
+	if err := checkKey(key); err != nil {
+		return Key{}, err
+	}
+
+	return key, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s is not PEM encoded", ErrInvalidKey, path)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q in %s", ErrInvalidKey, block.Type, path)
+	}
+}
+
+func loadJWKS(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidKey, path, err)
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for _, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		key, err := keyFromJWK(raw)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", raw.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func keyFromJWK(raw jwk) (Key, error) {
+	key := Key{ID: raw.Kid, Algorithm: raw.Alg}
+	if key.Algorithm == "" {
+		key.Algorithm = jwkAlgorithms[raw.Kty]
+	}
+
+	switch raw.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(raw.K)
+		if err != nil {
+			return Key{}, fmt.Errorf("%w: k: %v", ErrInvalidKey, err)
+		}
+		key.Secret = secret
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(raw.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("%w: n: %v", ErrInvalidKey, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(raw.E)
+		if err != nil {
+			return Key{}, fmt.Errorf("%w: e: %v", ErrInvalidKey, err)
+		}
+		key.PublicKey = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "OKP":
+		if raw.Crv != "Ed25519" {
+			return Key{}, fmt.Errorf("%w: curve %q", ErrUnsupportedAlgorithm, raw.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(raw.X)
+		if err != nil {
+			return Key{}, fmt.Errorf("%w: x: %v", ErrInvalidKey, err)
+		}
+		key.PublicKey = ed25519.PublicKey(x)
+	default:
+		return Key{}, fmt.Errorf("%w: key type %q", ErrUnsupportedAlgorithm, raw.Kty)
+	}
+
+	if err := checkKey(key); err != nil {
+		return Key{}, err
+	}
+
+	return key, nil
+}
+
+// checkKey проверяет, что тип ключа соответствует заявленному алгоритму.
+func checkKey(key Key) error {
+	if _, ok := hashes[key.Algorithm]; !ok && key.Algorithm != "EdDSA" {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, key.Algorithm)
+	}
+
+	switch {
+	case strings.HasPrefix(key.Algorithm, "HS"):
+		if len(key.Secret) == 0 {
+			return fmt.Errorf("%w: empty HMAC secret", ErrInvalidKey)
+		}
+	case strings.HasPrefix(key.Algorithm, "RS"):
+		publicKey, ok := key.PublicKey.(*rsa.PublicKey)
+		if !ok || publicKey.N.BitLen() < 2048 {
+			return fmt.Errorf("%w: %s requires an RSA key of at least 2048 bits", ErrInvalidKey, key.Algorithm)
+		}
+	case key.Algorithm == "EdDSA":
+		publicKey, ok := key.PublicKey.(ed25519.PublicKey)
+		if !ok || len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: EdDSA requires an Ed25519 key", ErrInvalidKey)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS movie_actors_actor_id_idx;
+DROP INDEX IF EXISTS movies_title_prefix_idx;
+DROP INDEX IF EXISTS movies_title_id_idx;
+DROP INDEX IF EXISTS movies_release_date_id_idx;
+DROP INDEX IF EXISTS movies_rating_id_idx;
@@ -0,0 +1,10 @@
+-- Keyset-пагинация идёт по (колонка сортировки, id).
+CREATE INDEX movies_rating_id_idx ON movies (rating, id);
+CREATE INDEX movies_release_date_id_idx ON movies (release_date, id);
+CREATE INDEX movies_title_id_idx ON movies (title, id);
+
+-- Фильтр title_prefix (LIKE 'prefix%') не зависит от правил сортировки базы.
+CREATE INDEX movies_title_prefix_idx ON movies (title varchar_pattern_ops);
+
+-- Фильтр actor_id и фильмография актёра.
+CREATE INDEX movie_actors_actor_id_idx ON movie_actors (actor_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS actors_search_vector_idx;
+DROP INDEX IF EXISTS movies_search_vector_idx;
+ALTER TABLE actors DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE movies DROP COLUMN IF EXISTS search_vector;
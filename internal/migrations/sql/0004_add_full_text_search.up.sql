@@ -0,0 +1,15 @@
+-- Названия и описания бывают и на русском, и на английском, поэтому вектор
+-- строится обоими словарями. Имена актёров не стеммятся (словарь simple).
+ALTER TABLE movies ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian'::regconfig, coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english'::regconfig, coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian'::regconfig, coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english'::regconfig, coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE actors ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple'::regconfig, coalesce(name, ''))
+) STORED;
+
+CREATE INDEX movies_search_vector_idx ON movies USING GIN (search_vector);
+CREATE INDEX actors_search_vector_idx ON actors USING GIN (search_vector);
@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Нечёткий поиск (%, <%) и автодополнение (ILIKE 'prefix%') по именам и названиям.
+CREATE INDEX actors_name_trgm_idx ON actors USING GIN (name gin_trgm_ops);
+CREATE INDEX movies_title_trgm_idx ON movies USING GIN (title gin_trgm_ops);
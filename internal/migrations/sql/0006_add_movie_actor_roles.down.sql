@@ -0,0 +1,3 @@
+ALTER TABLE movie_actors
+    DROP COLUMN IF EXISTS billing,
+    DROP COLUMN IF EXISTS character_name;
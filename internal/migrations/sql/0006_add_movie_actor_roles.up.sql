@@ -0,0 +1,3 @@
+ALTER TABLE movie_actors
+    ADD COLUMN character_name VARCHAR(255),
+    ADD COLUMN billing INT CHECK (billing > 0);
@@ -0,0 +1 @@
+ALTER TABLE movie_actors DROP COLUMN IF EXISTS credit_type;
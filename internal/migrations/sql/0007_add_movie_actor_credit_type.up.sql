@@ -0,0 +1,2 @@
+ALTER TABLE movie_actors
+    ADD COLUMN credit_type VARCHAR(16) CHECK (credit_type IN ('lead', 'supporting', 'cameo', 'voice'));
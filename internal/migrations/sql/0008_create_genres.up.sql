@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL
+);
+
+-- Жанры различаются без учёта регистра.
+CREATE UNIQUE INDEX genres_name_key ON genres (lower(name));
+
+CREATE TABLE IF NOT EXISTS movie_genres (
+    movie_id INT,
+    genre_id INT,
+    PRIMARY KEY (movie_id, genre_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE,
+    FOREIGN KEY (genre_id) REFERENCES genres(id) ON DELETE CASCADE
+);
+
+-- Фильтр genre_id и фасеты по жанрам.
+CREATE INDEX movie_genres_genre_id_idx ON movie_genres (genre_id);
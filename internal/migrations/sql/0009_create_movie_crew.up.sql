@@ -0,0 +1,13 @@
+-- Съёмочная группа. Люди хранятся в actors: один человек может быть и актёром,
+-- и режиссёром, и сценаристом.
+CREATE TABLE IF NOT EXISTS movie_crew (
+    movie_id INT,
+    person_id INT,
+    job VARCHAR(32) NOT NULL CHECK (job IN ('director', 'writer', 'producer', 'composer', 'cinematographer')),
+    PRIMARY KEY (movie_id, person_id, job),
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE,
+    FOREIGN KEY (person_id) REFERENCES actors(id) ON DELETE CASCADE
+);
+
+-- Поиск фильмов по режиссёру и фильмография человека в съёмочной группе.
+CREATE INDEX movie_crew_person_id_job_idx ON movie_crew (person_id, job);
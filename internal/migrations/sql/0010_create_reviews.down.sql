@@ -0,0 +1,5 @@
+ALTER TABLE movies
+    DROP COLUMN IF EXISTS vote_mean,
+    DROP COLUMN IF EXISTS vote_count;
+
+DROP TABLE IF EXISTS reviews;
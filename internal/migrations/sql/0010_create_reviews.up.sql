@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    movie_id INT NOT NULL,
+    user_id INT NOT NULL,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 0 AND 10),
+    body TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'published' CHECK (status IN ('published', 'hidden')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (movie_id, user_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX reviews_user_id_idx ON reviews (user_id);
+
+-- Число опубликованных оценок и их среднее. Колонка rating пересчитывается из
+-- них в той же транзакции, что и изменение отзыва.
+ALTER TABLE movies
+    ADD COLUMN vote_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN vote_mean FLOAT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS movie_list_items;
+DROP TABLE IF EXISTS movie_lists;
+DROP TABLE IF EXISTS watched;
+DROP TABLE IF EXISTS watchlist;
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS watchlist (
+    user_id INT NOT NULL,
+    movie_id INT NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, movie_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE
+);
+
+-- Журнал просмотров: один фильм можно отметить несколько раз.
+CREATE TABLE IF NOT EXISTS watched (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    movie_id INT NOT NULL,
+    watched_on DATE NOT NULL DEFAULT current_date,
+    score SMALLINT CHECK (score BETWEEN 0 AND 10),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX watched_user_id_watched_on_idx ON watched (user_id, watched_on, id);
+
+CREATE TABLE IF NOT EXISTS movie_lists (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    public BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX movie_lists_user_id_idx ON movie_lists (user_id);
+
+CREATE TABLE IF NOT EXISTS movie_list_items (
+    list_id INT NOT NULL,
+    movie_id INT NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (list_id, movie_id),
+    FOREIGN KEY (list_id) REFERENCES movie_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+-- Похожие фильмы пересчитываются фоновой задачей целиком; запросы
+-- /movies/{id}/similar и /me/recommendations только читают таблицу.
+CREATE TABLE IF NOT EXISTS movie_similarities (
+    movie_id INT NOT NULL,
+    similar_id INT NOT NULL,
+    score FLOAT NOT NULL,
+    shared_actors INT NOT NULL DEFAULT 0,
+    shared_genres INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (movie_id, similar_id),
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE,
+    FOREIGN KEY (similar_id) REFERENCES movies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX movie_similarities_movie_id_score_idx ON movie_similarities (movie_id, score DESC);
@@ -0,0 +1,156 @@
+package rbac
+
+import (
+	"TestVK/internal/config"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Permission string
+
+const (
+	MoviesRead      Permission = "movies:read"
+	MoviesWrite     Permission = "movies:write"
+	MoviesDelete    Permission = "movies:delete"
+	ActorsRead      Permission = "actors:read"
+	ActorsWrite     Permission = "actors:write"
+	ActorsDelete    Permission = "actors:delete"
+	ReviewsWrite    Permission = "reviews:write"
+	ReviewsModerate Permission = "reviews:moderate"
+	UsersManage     Permission = "users:manage"
+	PermissionsRead Permission = "permissions:read"
+)
+
+const (
+	// Authenticated - право, которое есть у любого пользователя с действующим токеном.
+	Authenticated Permission = ""
+	// All выдаёт все права сразу; как требование означает доступ только для ролей с "*".
+	All Permission = "*"
+)
+
+const wildcard = string(All)
+
+var ErrUnknownRole = errors.New("unknown role")
+
+// DefaultRoles используется, если в конфигурации нет секции rbac.
+func DefaultRoles() map[string]config.RoleConfig {
+	return map[string]config.RoleConfig{
+		"user": {
+			Permissions: []string{string(MoviesRead), string(ActorsRead), string(ReviewsWrite)},
+		},
+		"editor": {
+			Inherits:    []string{"user"},
+			Permissions: []string{string(MoviesWrite), string(ActorsWrite)},
+		},
+		"moderator": {
+			Inherits:    []string{"editor"},
+			Permissions: []string{string(MoviesDelete), string(ActorsDelete), string(ReviewsModerate)},
+		},
+		"admin": {
+			Permissions: []string{wildcard},
+		},
+	}
+}
+
+// Policy хранит развёрнутый набор прав каждой роли с учётом наследования.
+type Policy struct {
+	roles map[string][]string
+}
+
+// Default возвращает политику из DefaultRoles.
+func Default() *Policy {
+	policy, err := NewPolicy(config.RBACConfig{})
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+func NewPolicy(cfg config.RBACConfig) (*Policy, error) {
+	roles := cfg.Roles
+	if len(roles) == 0 {
+		roles = DefaultRoles()
+	}
+
+	policy := &Policy{roles: make(map[string][]string, len(roles))}
+	for name := range roles {
+		permissions, err := expand(roles, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		policy.roles[name] = permissions
+	}
+
+	return policy, nil
+}
+
+func expand(roles map[string]config.RoleConfig, name string, path []string) ([]string, error) {
+	for _, visited := range path {
+		if visited == name {
+			return nil, fmt.Errorf("role inheritance cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+	}
+
+	role, ok := roles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRole, name)
+	}
+
+	seen := make(map[string]struct{})
+	for _, parent := range role.Inherits {
+		inherited, err := expand(roles, parent, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range inherited {
+			seen[permission] = struct{}{}
+		}
+	}
+	for _, permission := range role.Permissions {
+		seen[permission] = struct{}{}
+	}
+
+	permissions := make([]string, 0, len(seen))
+	for permission := range seen {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	return permissions, nil
+}
+
+// Allowed проверяет право роли. Поддерживаются шаблоны "*" и "movies:*".
+func (p *Policy) Allowed(role string, permission Permission) bool {
+	if permission == Authenticated {
+		return true
+	}
+
+	for _, granted := range p.roles[role] {
+		if matches(granted, string(permission)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions возвращает права роли в том виде, в котором они заданы в политике.
+func (p *Policy) Permissions(role string) []string {
+	return append([]string(nil), p.roles[role]...)
+}
+
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+func matches(granted, permission string) bool {
+	if granted == wildcard || granted == permission {
+		return true
+	}
+	if resource, ok := strings.CutSuffix(granted, ":"+wildcard); ok {
+		return strings.HasPrefix(permission, resource+":")
+	}
+	return false
+}